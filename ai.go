@@ -0,0 +1,350 @@
+package main
+
+import "math"
+
+// The enemy AI is a small behavior tree. Each tick an enemy's tree is ticked with
+// an AIContext describing what the enemy can see; the behaviors write what the
+// enemy wants to do into ctx.Intent and the Orc carries it out.
+//
+// Nothing in this file depends on ebiten, so brains can be driven headlessly.
+
+// AIStatus is the result of ticking a behavior
+type AIStatus int
+
+const (
+	AIFailure AIStatus = iota // The behavior doesn't apply right now, try the next one
+	AISuccess                 // The behavior finished
+	AIRunning                 // The behavior is in control and wants to keep going
+)
+
+// Behavior is a node in an enemy's behavior tree
+type Behavior interface {
+	Tick(ctx *AIContext) AIStatus
+}
+
+// AIAgent is an enemy as its brain sees it: its body plus the memory
+// behaviors keep between ticks
+type AIAgent struct {
+	// Body, refreshed by the enemy before every tick
+	X          float64
+	Y          float64 // Depth in the arena, larger is closer to the camera
+	Speed      float64 // Walk speed in pixels per tick
+	Reach      float64 // Reach of the next attack in pixels
+	BodyWidth  float64 // Width of the collision box in pixels, used for spacing
+	FacingLeft bool
+	Health     int
+	MaxHealth  int
+	TookHit    bool // Took damage since the last tick
+
+	// Patrol area, used until the player comes close
+	PatrolLeft  float64 // Left boundary of patrol area
+	PatrolRight float64 // Right boundary of patrol area
+	MovingRight bool    // Direction of patrol movement
+	Aggro       bool    // Whether the enemy has noticed the player
+
+	RetreatTimer float64 // Seconds of retreating left
+}
+
+// AIIntent is what a brain wants its enemy to do this tick
+type AIIntent struct {
+	MoveX  float64 // Horizontal movement in pixels, negative is left
+	MoveY  float64 // Depth movement in pixels, negative is away from the camera
+	Face   int     // -1 to face left, 1 to face right, 0 to face the way it moves
+	Attack bool    // Swing if the player is in reach
+}
+
+// AIContext is everything a behavior can see while it's ticked
+type AIContext struct {
+	Self             *AIAgent
+	PlayerX          float64
+	PlayerY          float64
+	PlayerFacingLeft bool
+	HitDepth         float64   // How far apart in depth fighters can be and still hit each other
+	Crowd            []float64 // X of every living enemy, including Self
+	SelfIndex        int       // Index of Self in Crowd
+	Intent           AIIntent
+}
+
+// Tick runs a brain for one tick and returns its intent
+func (ctx *AIContext) Tick(brain Behavior) AIIntent {
+	ctx.Intent = AIIntent{}
+	brain.Tick(ctx)
+	ctx.Self.TookHit = false
+	return ctx.Intent
+}
+
+// aiArriveDistance is how close counts as "there", so enemies stop instead of
+// jittering back and forth across their target
+const aiArriveDistance = 4.0
+
+// moveTowards walks the agent towards a target x at the given speed.
+// It returns true once the agent has arrived.
+func (ctx *AIContext) moveTowards(targetX, speed float64) bool {
+	dx := targetX - ctx.Self.X
+	if math.Abs(dx) <= math.Max(aiArriveDistance, speed) {
+		ctx.Intent.MoveX = 0
+		return true
+	}
+	ctx.Intent.MoveX = math.Copysign(speed, dx)
+	return false
+}
+
+// aiDepthSpeed is how fast enemies walk up and down the arena, as a fraction of
+// their walk speed. Slower depth movement reads better from a side-on camera.
+const aiDepthSpeed = 0.6
+
+// alignDepth walks the agent up or down the arena until it's comfortably within
+// hitting depth of the player. It doesn't need to line up exactly, which keeps
+// a crowd from collapsing onto a single line.
+func (ctx *AIContext) alignDepth() {
+	dy := ctx.PlayerY - ctx.Self.Y
+	if math.Abs(dy) <= ctx.HitDepth*0.5 {
+		ctx.Intent.MoveY = 0
+		return
+	}
+	ctx.Intent.MoveY = math.Copysign(math.Min(ctx.Self.Speed*aiDepthSpeed, math.Abs(dy)), dy)
+}
+
+// inHitDepth reports whether the agent is close enough in depth to hit the player
+func (ctx *AIContext) inHitDepth() bool {
+	return math.Abs(ctx.PlayerY-ctx.Self.Y) <= ctx.HitDepth
+}
+
+// facePlayer turns the agent towards the player
+func (ctx *AIContext) facePlayer() {
+	if ctx.PlayerX < ctx.Self.X {
+		ctx.Intent.Face = -1
+	} else {
+		ctx.Intent.Face = 1
+	}
+}
+
+// playerDistance returns the horizontal distance to the player.
+// Depth is handled separately, since enemies line up in depth as they approach.
+func (ctx *AIContext) playerDistance() float64 {
+	return math.Abs(ctx.PlayerX - ctx.Self.X)
+}
+
+// side returns which side of the player the agent is on: -1 left, 1 right
+func (ctx *AIContext) side() float64 {
+	if ctx.Self.X < ctx.PlayerX {
+		return -1
+	}
+	return 1
+}
+
+// engage moves the agent to a spot next to the player on the given side, lined
+// up in depth, and asks for an attack once it's in reach
+func (ctx *AIContext) engage(side float64) {
+	// Stand a little inside reach so small knockbacks don't push it out again
+	ctx.moveTowards(ctx.PlayerX+side*ctx.Self.Reach*0.8, ctx.Self.Speed)
+	ctx.alignDepth()
+	if ctx.playerDistance() <= ctx.Self.Reach && ctx.inHitDepth() {
+		ctx.facePlayer()
+		ctx.Intent.Attack = true
+	}
+}
+
+// Selector ticks its children in order until one doesn't fail
+type Selector []Behavior
+
+// Tick implements Behavior
+func (s Selector) Tick(ctx *AIContext) AIStatus {
+	for _, child := range s {
+		if status := child.Tick(ctx); status != AIFailure {
+			return status
+		}
+	}
+	return AIFailure
+}
+
+// Sequence ticks its children in order until one doesn't succeed
+type Sequence []Behavior
+
+// Tick implements Behavior
+func (s Sequence) Tick(ctx *AIContext) AIStatus {
+	for _, child := range s {
+		if status := child.Tick(ctx); status != AISuccess {
+			return status
+		}
+	}
+	return AISuccess
+}
+
+// Condition succeeds when its function returns true
+type Condition func(ctx *AIContext) bool
+
+// Tick implements Behavior
+func (c Condition) Tick(ctx *AIContext) AIStatus {
+	if c(ctx) {
+		return AISuccess
+	}
+	return AIFailure
+}
+
+// Chase walks straight at the player and attacks when in reach
+type Chase struct{}
+
+// Tick implements Behavior
+func (Chase) Tick(ctx *AIContext) AIStatus {
+	ctx.engage(ctx.side())
+	return AIRunning
+}
+
+// KeepDistance holds the agent between Min and Max pixels from the player,
+// facing them and lined up in depth. With Attack set it attacks whenever it's
+// inside that band, which suits enemies whose reach is a throwing range.
+type KeepDistance struct {
+	Min, Max float64
+	Attack   bool
+}
+
+// Tick implements Behavior
+func (k KeepDistance) Tick(ctx *AIContext) AIStatus {
+	distance := ctx.playerDistance()
+	switch {
+	case distance < k.Min:
+		ctx.moveTowards(ctx.PlayerX+ctx.side()*k.Min, ctx.Self.Speed)
+	case distance > k.Max:
+		ctx.moveTowards(ctx.PlayerX+ctx.side()*k.Max, ctx.Self.Speed)
+	default:
+		ctx.Intent.MoveX = 0
+		ctx.Intent.Attack = k.Attack && ctx.inHitDepth()
+	}
+	ctx.alignDepth()
+	ctx.facePlayer()
+	return AIRunning
+}
+
+// Flank circles to the side the player isn't facing and attacks from behind
+type Flank struct{}
+
+// Tick implements Behavior
+func (Flank) Tick(ctx *AIContext) AIStatus {
+	behind := -1.0
+	if ctx.PlayerFacingLeft {
+		behind = 1
+	}
+	ctx.engage(behind)
+	return AIRunning
+}
+
+// RetreatWhenHurt backs away from the player for Duration seconds after taking
+// a hit that leaves the agent at or below the Below fraction of its health
+type RetreatWhenHurt struct {
+	Below    float64 // Health fraction that triggers a retreat (1 retreats on any hit)
+	Duration float64 // Seconds to retreat for
+	Speed    float64 // Retreat speed as a multiple of walk speed
+}
+
+// Tick implements Behavior
+func (r RetreatWhenHurt) Tick(ctx *AIContext) AIStatus {
+	self := ctx.Self
+	if self.TookHit && float64(self.Health) <= r.Below*float64(self.MaxHealth) {
+		self.RetreatTimer = r.Duration
+	}
+	if self.RetreatTimer <= 0 {
+		return AIFailure
+	}
+
+	self.RetreatTimer -= 1.0 / 60.0
+	ctx.Intent.MoveX = ctx.side() * self.Speed * r.Speed
+	ctx.facePlayer() // Back off without turning its back
+	return AIRunning
+}
+
+// Surround spreads enemies around the player in ranks on both sides instead of
+// letting them stack on the same pixel. Only the front rank on each side
+// engages; the rest wait their turn Spacing body widths apart, at whatever
+// depth they happen to be, so the queue doesn't form a single file.
+type Surround struct {
+	Spacing float64 // Gap between ranks in body widths
+}
+
+// Tick implements Behavior
+func (s Surround) Tick(ctx *AIContext) AIStatus {
+	side := ctx.side()
+	rank, sameSide, otherSide, last := ctx.surroundRank(side)
+
+	// The last enemy in the crowd on a crowded side crosses over to even things
+	// out. Choosing by crowd order rather than by distance keeps the choice
+	// steady while it walks past the others, who would otherwise take turns
+	// being rearmost and never cross.
+	if last && sameSide > otherSide+1 {
+		side, rank = -side, otherSide
+	}
+
+	if rank == 0 {
+		ctx.engage(side)
+		return AIRunning
+	}
+
+	gap := ctx.Self.BodyWidth * s.Spacing
+	ctx.moveTowards(ctx.PlayerX+side*(ctx.Self.Reach*0.8+float64(rank)*gap), ctx.Self.Speed)
+	if ctx.Intent.MoveX == 0 {
+		ctx.facePlayer()
+	}
+	return AIRunning
+}
+
+// surroundRank returns how many enemies on the given side of the player are
+// closer to the player than Self, along with how many enemies are on each side
+// and whether Self comes last in the crowd on its side. Ties are broken by
+// crowd index so two enemies on the same spot get different ranks.
+func (ctx *AIContext) surroundRank(side float64) (rank, sameSide, otherSide int, last bool) {
+	myDistance := ctx.playerDistance()
+	last = true
+	for i, x := range ctx.Crowd {
+		otherSideOfPlayer := (x < ctx.PlayerX) != (side < 0)
+		if otherSideOfPlayer {
+			otherSide++
+			continue
+		}
+		sameSide++
+		if i == ctx.SelfIndex {
+			continue
+		}
+		if i > ctx.SelfIndex {
+			last = false
+		}
+		distance := math.Abs(x - ctx.PlayerX)
+		if distance < myDistance || (distance == myDistance && i < ctx.SelfIndex) {
+			rank++
+		}
+	}
+	return rank, sameSide, otherSide, last
+}
+
+// Patrol walks back and forth across the patrol area until the player comes
+// within Aggro pixels or the agent is hurt. After that it fails forever, so
+// the next behavior takes over.
+type Patrol struct {
+	Aggro float64
+}
+
+// Tick implements Behavior
+func (p Patrol) Tick(ctx *AIContext) AIStatus {
+	self := ctx.Self
+	if self.Aggro {
+		return AIFailure
+	}
+	if ctx.playerDistance() <= p.Aggro || self.Health < self.MaxHealth {
+		self.Aggro = true
+		return AIFailure
+	}
+
+	// Turn around at the edges of the patrol area
+	if self.X >= self.PatrolRight {
+		self.MovingRight = false
+	} else if self.X <= self.PatrolLeft {
+		self.MovingRight = true
+	}
+
+	speed := self.Speed * 0.5 // Stroll rather than march
+	if self.MovingRight {
+		ctx.Intent.MoveX = speed
+	} else {
+		ctx.Intent.MoveX = -speed
+	}
+	return AIRunning
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// stubBehavior returns a fixed status and counts how often it was ticked
+type stubBehavior struct {
+	status AIStatus
+	ticks  int
+}
+
+func (s *stubBehavior) Tick(ctx *AIContext) AIStatus {
+	s.ticks++
+	return s.status
+}
+
+// newTestAgent returns an agent with a typical orc's body at x, full health
+func newTestAgent(x float64) *AIAgent {
+	return &AIAgent{X: x, Speed: 2, Reach: 60, BodyWidth: 40, Health: 10, MaxHealth: 10}
+}
+
+// newTestContext returns a context for a lone agent facing the player at playerX
+func newTestContext(self *AIAgent, playerX float64) *AIContext {
+	return &AIContext{Self: self, PlayerX: playerX, HitDepth: 30, Crowd: []float64{self.X}}
+}
+
+func TestSelectorFallsThroughFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []AIStatus
+		want     AIStatus
+		ticked   []int
+	}{
+		{"first succeeds", []AIStatus{AISuccess, AIRunning}, AISuccess, []int{1, 0}},
+		{"first fails", []AIStatus{AIFailure, AIRunning, AISuccess}, AIRunning, []int{1, 1, 0}},
+		{"all fail", []AIStatus{AIFailure, AIFailure}, AIFailure, []int{1, 1}},
+		{"empty", nil, AIFailure, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubs, selector := stubTree(tt.statuses)
+			if got := Selector(selector).Tick(newTestContext(newTestAgent(0), 100)); got != tt.want {
+				t.Errorf("status = %v, want %v", got, tt.want)
+			}
+			checkTicks(t, stubs, tt.ticked)
+		})
+	}
+}
+
+func TestSequenceStopsAtFirstNonSuccess(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []AIStatus
+		want     AIStatus
+		ticked   []int
+	}{
+		{"all succeed", []AIStatus{AISuccess, AISuccess}, AISuccess, []int{1, 1}},
+		{"condition fails", []AIStatus{AIFailure, AIRunning}, AIFailure, []int{1, 0}},
+		{"child running", []AIStatus{AISuccess, AIRunning, AISuccess}, AIRunning, []int{1, 1, 0}},
+		{"empty", nil, AISuccess, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubs, sequence := stubTree(tt.statuses)
+			if got := Sequence(sequence).Tick(newTestContext(newTestAgent(0), 100)); got != tt.want {
+				t.Errorf("status = %v, want %v", got, tt.want)
+			}
+			checkTicks(t, stubs, tt.ticked)
+		})
+	}
+}
+
+// stubTree returns stubs with the given statuses, and the same stubs as behaviors
+func stubTree(statuses []AIStatus) ([]*stubBehavior, []Behavior) {
+	var stubs []*stubBehavior
+	var behaviors []Behavior
+	for _, status := range statuses {
+		stub := &stubBehavior{status: status}
+		stubs = append(stubs, stub)
+		behaviors = append(behaviors, stub)
+	}
+	return stubs, behaviors
+}
+
+// checkTicks checks how often each stub was ticked
+func checkTicks(t *testing.T, stubs []*stubBehavior, want []int) {
+	t.Helper()
+	for i, stub := range stubs {
+		if stub.ticks != want[i] {
+			t.Errorf("child %d ticked %d times, want %d", i, stub.ticks, want[i])
+		}
+	}
+}
+
+func TestChaseWalksTowardsThePlayer(t *testing.T) {
+	tests := []struct {
+		name       string
+		x, playerX float64
+		wantMove   float64 // Sign of the horizontal move
+		wantAttack bool
+	}{
+		{"player to the right", 0, 500, 1, false},
+		{"player to the left", 500, 0, -1, false},
+		{"in reach", 0, 50, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := newTestContext(newTestAgent(tt.x), tt.playerX)
+			intent := ctx.Tick(Chase{})
+			if sign(intent.MoveX) != tt.wantMove {
+				t.Errorf("MoveX = %v, want sign %v", intent.MoveX, tt.wantMove)
+			}
+			if intent.Attack != tt.wantAttack {
+				t.Errorf("Attack = %v, want %v", intent.Attack, tt.wantAttack)
+			}
+			if tt.wantAttack && intent.Face != int(sign(tt.playerX-tt.x)) {
+				t.Errorf("Face = %d, want to face the player", intent.Face)
+			}
+		})
+	}
+}
+
+func TestKeepDistanceHoldsInsideItsBand(t *testing.T) {
+	keep := KeepDistance{Min: 200, Max: 300, Attack: true}
+	tests := []struct {
+		name       string
+		distance   float64
+		wantMove   float64 // Sign of the horizontal move, with the player to the right
+		wantAttack bool
+	}{
+		{"too close backs off", 100, -1, false},
+		{"too far closes in", 400, 1, false},
+		{"at the near edge holds", 200, 0, true},
+		{"inside holds", 250, 0, true},
+		{"at the far edge holds", 300, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := newTestContext(newTestAgent(1000-tt.distance), 1000)
+			intent := ctx.Tick(keep)
+			if sign(intent.MoveX) != tt.wantMove {
+				t.Errorf("MoveX = %v, want sign %v", intent.MoveX, tt.wantMove)
+			}
+			if intent.Attack != tt.wantAttack {
+				t.Errorf("Attack = %v, want %v", intent.Attack, tt.wantAttack)
+			}
+		})
+	}
+
+	// Pushed out of the band it walks back to the edge and stays put there,
+	// rather than overshooting and turning back and forth
+	self := newTestAgent(1000 - 100)
+	for tick := 0; tick < 300; tick++ {
+		ctx := newTestContext(self, 1000)
+		self.X += ctx.Tick(keep).MoveX
+	}
+	distance := 1000 - self.X
+	if distance < keep.Min-aiArriveDistance || distance > keep.Max {
+		t.Fatalf("settled %v from the player, want within [%v, %v]", distance, keep.Min, keep.Max)
+	}
+	if intent := newTestContext(self, 1000).Tick(keep); intent.MoveX != 0 {
+		t.Errorf("still moving %v once settled", intent.MoveX)
+	}
+}
+
+func TestSurroundGivesEveryEnemyItsOwnSpot(t *testing.T) {
+	surround := Surround{Spacing: 1.5}
+	const playerX = 1000
+
+	// Everyone starts on the same pixel, the worst case for stacking
+	agents := make([]*AIAgent, 6)
+	for i := range agents {
+		agents[i] = newTestAgent(1400)
+	}
+	crowd := make([]float64, len(agents))
+	for tick := 0; tick < 1200; tick++ {
+		for i, agent := range agents {
+			crowd[i] = agent.X
+		}
+		for i, agent := range agents {
+			ctx := &AIContext{Self: agent, PlayerX: playerX, HitDepth: 30, Crowd: crowd, SelfIndex: i}
+			agent.X += ctx.Tick(surround).MoveX
+		}
+	}
+
+	left := 0
+	for i, a := range agents {
+		if a.X < playerX {
+			left++
+		}
+		for j, b := range agents[:i] {
+			if gap := math.Abs(a.X - b.X); gap < a.BodyWidth {
+				t.Errorf("enemies %d and %d are %v apart at %v and %v, want at least a body width", j, i, gap, b.X, a.X)
+			}
+		}
+	}
+	if left == 0 || left == len(agents) {
+		t.Errorf("%d of %d enemies on the left, want them spread over both sides", left, len(agents))
+	}
+}
+
+func TestRetreatWhenHurt(t *testing.T) {
+	retreat := RetreatWhenHurt{Below: 0.5, Duration: 1, Speed: 1.5}
+
+	// A scratch above the threshold doesn't send it running
+	self := newTestAgent(0)
+	self.Health, self.TookHit = 8, true
+	if status := retreat.Tick(newTestContext(self, 100)); status != AIFailure {
+		t.Fatalf("status after a light hit = %v, want failure", status)
+	}
+
+	// A heavy hit backs it away from the player, still facing them
+	self.Health, self.TookHit = 4, true
+	ctx := newTestContext(self, 100)
+	intent := ctx.Tick(retreat)
+	if want := -self.Speed * retreat.Speed; intent.MoveX != want {
+		t.Errorf("MoveX = %v, want %v away from the player", intent.MoveX, want)
+	}
+	if intent.Face != 1 {
+		t.Errorf("Face = %d, want to keep facing the player", intent.Face)
+	}
+
+	// It gives up after Duration seconds
+	ticks := 1
+	for ; ticks < 120; ticks++ {
+		if retreat.Tick(newTestContext(self, 100)) == AIFailure {
+			break
+		}
+	}
+	if want := int(retreat.Duration * 60); math.Abs(float64(ticks-want)) > 1 {
+		t.Errorf("retreated for %d ticks, want about %d", ticks, want)
+	}
+}
+
+func TestPatrolStaysInBounds(t *testing.T) {
+	patrol := Patrol{Aggro: 200}
+	self := newTestAgent(500)
+	self.PatrolLeft, self.PatrolRight = 400, 600
+
+	turns := 0
+	movingRight := self.MovingRight
+	for tick := 0; tick < 2000; tick++ {
+		ctx := newTestContext(self, 5000) // Far out of sight
+		if status := patrol.Tick(ctx); status != AIRunning {
+			t.Fatalf("tick %d: status = %v, want running", tick, status)
+		}
+		self.X += ctx.Intent.MoveX
+		if self.X < self.PatrolLeft-self.Speed || self.X > self.PatrolRight+self.Speed {
+			t.Fatalf("tick %d: wandered to %v, outside [%v, %v]", tick, self.X, self.PatrolLeft, self.PatrolRight)
+		}
+		if self.MovingRight != movingRight {
+			movingRight = self.MovingRight
+			turns++
+		}
+	}
+	if turns < 2 {
+		t.Errorf("turned %d times, want it to walk back and forth", turns)
+	}
+
+	// Spotting the player ends the patrol for good
+	ctx := newTestContext(self, self.X+100)
+	if status := patrol.Tick(ctx); status != AIFailure || ctx.Intent != (AIIntent{}) {
+		t.Errorf("spotting the player gave %v with intent %+v, want failure and no intent", status, ctx.Intent)
+	}
+	if !self.Aggro {
+		t.Error("not aggroed after the player came within range")
+	}
+	if status := patrol.Tick(newTestContext(self, 5000)); status != AIFailure {
+		t.Errorf("status once aggroed = %v, want failure", status)
+	}
+}
+
+// sign returns -1, 0 or 1
+func sign(v float64) float64 {
+	switch {
+	case v < 0:
+		return -1
+	case v > 0:
+		return 1
+	}
+	return 0
+}
@@ -16,6 +16,7 @@ type File struct {
 	Header *Header
 	Frames []*Frame
 	Tags   []*Tag
+	Layers []*Layer // In file order, which is the order of cels' LayerIndex
 }
 
 // Header represents the Aseprite file header
@@ -73,6 +74,21 @@ type Cel struct {
 	Pixels     []byte
 }
 
+// Layer represents a layer, as listed in the first frame
+type Layer struct {
+	Flags      uint16
+	Type       uint16 // 0 for normal layers, 1 for groups, 2 for tilemaps
+	ChildLevel uint16
+	Opacity    uint8
+	Name       string
+}
+
+// Layer flag constants
+const (
+	LayerFlagVisible  = 1
+	LayerFlagEditable = 2
+)
+
 // Tag represents an animation tag
 type Tag struct {
 	Name      string
@@ -120,6 +136,7 @@ func ParseFile(data []byte) (*File, error) {
 		Header: header,
 		Frames: make([]*Frame, header.Frames),
 		Tags:   []*Tag{},
+		Layers: []*Layer{},
 	}
 
 	// Read frames
@@ -132,11 +149,17 @@ func ParseFile(data []byte) (*File, error) {
 
 		// Process chunks to find tags
 		for _, chunk := range frame.Chunks {
-			if chunk.Type == 0x2018 { // Tags chunk
+			switch chunk.Type {
+			case 0x2018: // Tags chunk
 				tags, err := parseTagsChunk(chunk.Data)
 				if err == nil {
 					file.Tags = append(file.Tags, tags...)
 				}
+			case 0x2004: // Layer chunk
+				layer, err := parseLayerChunk(chunk.Data)
+				if err == nil {
+					file.Layers = append(file.Layers, layer)
+				}
 			}
 		}
 	}
@@ -146,6 +169,22 @@ func ParseFile(data []byte) (*File, error) {
 
 // GetFrameImage extracts an image from a specific frame
 func (f *File) GetFrameImage(frameIndex int) (image.Image, error) {
+	return f.GetFrameImageLayers(frameIndex, nil)
+}
+
+// LayerIndex returns the index of the layer with the given name, or -1 if there is none
+func (f *File) LayerIndex(name string) int {
+	for i, layer := range f.Layers {
+		if layer.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetFrameImageLayers extracts an image from a specific frame, drawing only the
+// layers include accepts. A nil include draws every layer.
+func (f *File) GetFrameImageLayers(frameIndex int, include func(layerIndex int) bool) (image.Image, error) {
 	if frameIndex >= len(f.Frames) {
 		return nil, fmt.Errorf("frame index %d out of range", frameIndex)
 	}
@@ -160,6 +199,9 @@ func (f *File) GetFrameImage(frameIndex int) (image.Image, error) {
 			if err != nil {
 				continue // Skip invalid cels
 			}
+			if include != nil && !include(int(cel.LayerIndex)) {
+				continue // Skip layers the caller left out
+			}
 
 			// Draw cel to image
 			err = drawCelToImage(img, cel, f.Header.ColorDepth)
@@ -409,11 +451,12 @@ func drawCelToImage(img *image.RGBA, cel *Cel, colorDepth uint16) error {
 			// Apply cel opacity
 			c.A = uint8((uint16(c.A) * uint16(cel.Opacity)) / 255)
 
-			// Set pixel in image
+			// Blend the pixel over the layers below, so a cel's transparent
+			// pixels don't erase what's under them
 			imgX := int(cel.X) + x
 			imgY := int(cel.Y) + y
-			if imgX >= 0 && imgY >= 0 && imgX < img.Bounds().Dx() && imgY < img.Bounds().Dy() {
-				img.Set(imgX, imgY, c)
+			if c.A > 0 && imgX >= 0 && imgY >= 0 && imgX < img.Bounds().Dx() && imgY < img.Bounds().Dy() {
+				img.SetRGBA(imgX, imgY, blendOver(c, img.RGBAAt(imgX, imgY)))
 			}
 		}
 	}
@@ -421,6 +464,62 @@ func drawCelToImage(img *image.RGBA, cel *Cel, colorDepth uint16) error {
 	return nil
 }
 
+// blendOver composites a straight-alpha color over a premultiplied one
+func blendOver(src, dst color.RGBA) color.RGBA {
+	if src.A == 255 {
+		return src
+	}
+	// Premultiply the source, then add what shows through of the destination
+	a := uint32(src.A)
+	blend := func(s, d uint8) uint8 {
+		return uint8((uint32(s)*a + uint32(d)*(255-a)) / 255)
+	}
+	return color.RGBA{
+		R: blend(src.R, dst.R),
+		G: blend(src.G, dst.G),
+		B: blend(src.B, dst.B),
+		A: uint8(a + uint32(dst.A)*(255-a)/255),
+	}
+}
+
+func parseLayerChunk(data []byte) (*Layer, error) {
+	reader := bytes.NewReader(data)
+	layer := &Layer{}
+
+	if err := binary.Read(reader, binary.LittleEndian, &layer.Flags); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(reader, binary.LittleEndian, &layer.Type); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(reader, binary.LittleEndian, &layer.ChildLevel); err != nil {
+		return nil, err
+	}
+
+	// Skip default width and height (ignored) and blend mode
+	reader.Read(make([]byte, 6))
+
+	if err := binary.Read(reader, binary.LittleEndian, &layer.Opacity); err != nil {
+		return nil, err
+	}
+
+	// Skip reserved bytes
+	reader.Read(make([]byte, 3))
+
+	// Read layer name (STRING format: WORD length + bytes)
+	var nameLength uint16
+	if err := binary.Read(reader, binary.LittleEndian, &nameLength); err != nil {
+		return nil, err
+	}
+	name := make([]byte, nameLength)
+	if _, err := io.ReadFull(reader, name); err != nil {
+		return nil, err
+	}
+	layer.Name = string(name)
+
+	return layer, nil
+}
+
 func parseTagsChunk(data []byte) ([]*Tag, error) {
 	reader := bytes.NewReader(data)
 	var tags []*Tag
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+
+	"rpg_demo/aseprite"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// BackgroundLayer is one layer of a level's parallax background, as configured in levelsPath.
+// Layers are drawn in order, so the furthest layer comes first.
+type BackgroundLayer struct {
+	Image      string      `json:"image"`          // A .png, or an .aseprite file for an animated layer
+	Tag        string      `json:"tag"`            // Animation tag to loop in an .aseprite layer; empty plays every frame
+	Scale      float64     `json:"scale"`          // Size multiplier for the image
+	Scroll     [2]float64  `json:"scroll"`         // How far the layer follows the camera: 0 is fixed to the screen, 1 moves with the fighters
+	Offset     [2]float64  `json:"offset"`         // Top left corner of the image, relative to the center of the screen with the camera at the level center
+	TileX      bool        `json:"tileX"`          // Repeat the image across the screen horizontally
+	TileY      bool        `json:"tileY"`          // Repeat the image across the screen vertically
+	AutoScroll [2]float64  `json:"autoScroll"`     // Drift in pixels per second, e.g. for clouds
+	Tint       *[3]float64 `json:"tint,omitempty"` // RGB multipliers; omitted leaves the image unchanged
+}
+
+// Validate checks that the layer can be drawn. Image files are checked when they're loaded.
+func (l *BackgroundLayer) Validate() error {
+	var errs []error
+	switch filepath.Ext(l.Image) {
+	case ".png":
+		if l.Tag != "" {
+			errs = append(errs, fmt.Errorf("tag %q needs an .aseprite image, got %q", l.Tag, l.Image))
+		}
+	case ".aseprite":
+	default:
+		errs = append(errs, fmt.Errorf("image must be a .png or .aseprite file, got %q", l.Image))
+	}
+	if l.Scale <= 0 {
+		errs = append(errs, fmt.Errorf("scale must be positive, got %v", l.Scale))
+	}
+	for _, s := range l.Scroll {
+		if s < 0 {
+			errs = append(errs, fmt.Errorf("scroll cannot be negative, got %v", s))
+		}
+	}
+	if l.Tint != nil {
+		for _, c := range l.Tint {
+			if c < 0 {
+				errs = append(errs, fmt.Errorf("tint cannot be negative, got %v", c))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// defaultBackgroundFrameDuration is used for .aseprite frames saved without a duration
+const defaultBackgroundFrameDuration = 0.1
+
+// backgroundLayer is a loaded BackgroundLayer with its animation and drift state
+type backgroundLayer struct {
+	config    BackgroundLayer
+	frames    []*ebiten.Image
+	durations []float64 // Seconds each frame shows for
+
+	frame      int
+	frameTimer float64
+	driftX     float64
+	driftY     float64
+}
+
+// Background is a level's layered, parallax scrolling backdrop
+type Background struct {
+	layers []*backgroundLayer
+}
+
+// LoadBackground loads the images for a level's background layers.
+// Layers sharing an image file share the loaded frames.
+func LoadBackground(configs []BackgroundLayer) (*Background, error) {
+	type loadedImage struct {
+		sheet  *SpriteSheet // nil for a .png
+		frames []*ebiten.Image
+	}
+	loaded := map[string]*loadedImage{}
+
+	background := &Background{}
+	for i, config := range configs {
+		img, ok := loaded[config.Image]
+		if !ok {
+			img = &loadedImage{}
+			if filepath.Ext(config.Image) == ".aseprite" {
+				file, err := aseprite.LoadFile(config.Image)
+				if err != nil {
+					return nil, fmt.Errorf("background layer %d: failed to load %s: %w", i+1, config.Image, err)
+				}
+				img.sheet = &SpriteSheet{File: file, frames: make([]*ebiten.Image, len(file.Frames))}
+			} else {
+				frame, err := loadImageFromFile(config.Image)
+				if err != nil {
+					return nil, fmt.Errorf("background layer %d: failed to load %s: %w", i+1, config.Image, err)
+				}
+				img.frames = []*ebiten.Image{frame}
+			}
+			loaded[config.Image] = img
+		}
+
+		layer := &backgroundLayer{config: config}
+		if img.sheet == nil {
+			layer.frames = img.frames
+			layer.durations = []float64{defaultBackgroundFrameDuration}
+		} else {
+			from, to := 0, len(img.sheet.File.Frames)-1
+			if config.Tag != "" {
+				if from, to, ok = img.sheet.TagRange(config.Tag); !ok {
+					return nil, fmt.Errorf("background layer %d: %s has no tag %q", i+1, config.Image, config.Tag)
+				}
+			}
+			for index := from; index <= to; index++ {
+				frame, err := img.sheet.Frame(index)
+				if err != nil {
+					return nil, fmt.Errorf("background layer %d: %s: %w", i+1, config.Image, err)
+				}
+				duration := float64(img.sheet.File.Frames[index].Header.Duration) / 1000
+				if duration <= 0 {
+					duration = defaultBackgroundFrameDuration
+				}
+				layer.frames = append(layer.frames, frame)
+				layer.durations = append(layer.durations, duration)
+			}
+		}
+		if len(layer.frames) == 0 {
+			return nil, fmt.Errorf("background layer %d: %s has no frames", i+1, config.Image)
+		}
+		background.layers = append(background.layers, layer)
+	}
+	return background, nil
+}
+
+// Update advances animated layers and drifts auto-scrolling ones
+func (b *Background) Update() {
+	const dt = 1.0 / 60.0 // Assuming 60 FPS
+	for _, layer := range b.layers {
+		if len(layer.frames) > 1 {
+			layer.frameTimer += dt
+			for layer.frameTimer >= layer.durations[layer.frame] {
+				layer.frameTimer -= layer.durations[layer.frame]
+				layer.frame = (layer.frame + 1) % len(layer.frames)
+			}
+		}
+
+		layer.driftX += layer.config.AutoScroll[0] * dt
+		layer.driftY += layer.config.AutoScroll[1] * dt
+
+		// Tiled layers repeat, so wrapping the drift keeps it small without a visible jump
+		width, height := layer.size()
+		if layer.config.TileX {
+			layer.driftX = math.Mod(layer.driftX, width)
+		}
+		if layer.config.TileY {
+			layer.driftY = math.Mod(layer.driftY, height)
+		}
+	}
+}
+
+// size returns the layer's image size in pixels at its configured scale
+func (l *backgroundLayer) size() (width, height float64) {
+	bounds := l.frames[l.frame].Bounds()
+	return float64(bounds.Dx()) * l.config.Scale, float64(bounds.Dy()) * l.config.Scale
+}
+
+// Draw renders every layer through the camera. A layer follows the camera's
+// movement, zoom and shake in proportion to its scroll factor, so distant
+// layers with small factors barely move.
+func (b *Background) Draw(screen *ebiten.Image, camera *Camera) {
+	shakeX, shakeY := camera.shakeOffset()
+	centerX, centerY := float64(screenWidth)/2, float64(screenHeight)/2
+
+	for _, layer := range b.layers {
+		config := layer.config
+
+		// Zoom uses the horizontal factor for both axes so the image keeps its shape
+		zoom := 1 + (camera.Zoom-1)*config.Scroll[0]
+		width, height := layer.size()
+		width, height = width*zoom, height*zoom
+
+		x := centerX + (config.Offset[0]+layer.driftX-camera.X*config.Scroll[0])*zoom + shakeX*config.Scroll[0]
+		y := centerY + (config.Offset[1]+layer.driftY-camera.Y*config.Scroll[1])*zoom + shakeY*config.Scroll[1]
+
+		// Tiled axes start from the last copy left of (or above) the screen edge and repeat across it
+		columns, rows := 1, 1
+		if config.TileX {
+			x = tileStart(x, width)
+			columns = int(math.Ceil((float64(screenWidth) - x) / width))
+		}
+		if config.TileY {
+			y = tileStart(y, height)
+			rows = int(math.Ceil((float64(screenHeight) - y) / height))
+		}
+
+		opts := &ebiten.DrawImageOptions{}
+		if config.Tint != nil {
+			opts.ColorScale.Scale(float32(config.Tint[0]), float32(config.Tint[1]), float32(config.Tint[2]), 1)
+		}
+		for row := 0; row < rows; row++ {
+			for column := 0; column < columns; column++ {
+				opts.GeoM.Reset()
+				opts.GeoM.Scale(config.Scale*zoom, config.Scale*zoom)
+				opts.GeoM.Translate(x+float64(column)*width, y+float64(row)*height)
+				screen.DrawImage(layer.frames[layer.frame], opts)
+			}
+		}
+	}
+}
+
+// tileStart moves a tile's position by whole tiles to the last one starting at or before 0
+func tileStart(position, size float64) float64 {
+	position = math.Mod(position, size)
+	if position > 0 {
+		position -= size
+	}
+	return position
+}
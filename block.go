@@ -0,0 +1,192 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// blockFrame returns the frame held while blocking
+func (g *Game) blockFrame() int {
+	return g.playerTags[g.tuning.Player.Block.Tag][0]
+}
+
+// updateBlock raises the block while the block input is held. Only a fresh
+// press can parry; a block held through a swing or a hurt animation is raised
+// without a parry window, so holding the button down isn't a free parry. Nor
+// is tapping it: after the block is lowered, a fresh press only parries again
+// once the parry cooldown has passed.
+func (g *Game) updateBlock() {
+	const dt = 1.0 / 60.0 // Assuming 60 FPS
+
+	if g.critTimer > 0 {
+		g.critTimer -= dt
+	}
+	if g.parryCooldown > 0 {
+		g.parryCooldown -= dt
+	}
+	if g.guardSparkTimer > 0 {
+		g.guardSparkTimer -= dt
+	}
+
+	canBlock := g.playerState == PlayerStateAlive && !g.isAttacking && !g.isDodging
+	if !canBlock || !g.input.IsHeld(ActionBlock) {
+		if g.isBlocking {
+			g.isBlocking = false
+			g.parryCooldown = g.tuning.Player.Block.ParryCooldown
+			g.currentFrame = g.idleFrameStart
+			g.frameTimer = 0
+		}
+		return
+	}
+
+	if !g.isBlocking {
+		g.isBlocking = true
+		g.isWalking = false
+		g.blockTimer = 0
+		if !g.input.JustPressed(ActionBlock) || g.parryCooldown > 0 {
+			g.blockTimer = g.tuning.Player.Block.ParryWindow + dt
+		}
+		g.currentFrame = g.blockFrame()
+		g.frameTimer = 0
+	} else {
+		g.blockTimer += dt
+	}
+
+	// Turn to face a threat without lowering the guard
+	switch {
+	case g.input.IsHeld(ActionMoveLeft) && !g.input.IsHeld(ActionMoveRight):
+		g.facingLeft = true
+	case g.input.IsHeld(ActionMoveRight) && !g.input.IsHeld(ActionMoveLeft):
+		g.facingLeft = false
+	}
+}
+
+// blocksHitFrom reports whether the player's block covers a hit coming from sourceX
+func (g *Game) blocksHitFrom(sourceX float64) bool {
+	if !g.isBlocking {
+		return false
+	}
+	return (sourceX < g.positionX) == g.facingLeft
+}
+
+// receiveHit applies an incoming hit to the player. A frontal hit against a
+// raised block is softened, and one that lands within the parry window right
+// after raising it is parried and does nothing. It returns true on a parry so
+// the caller can punish the attacker.
+func (g *Game) receiveHit(damage, knockback, sourceX float64) (parried bool) {
+	if !g.blocksHitFrom(sourceX) {
+		g.damagePlayer(damage, knockback, sourceX)
+		return false
+	}
+
+	block := g.tuning.Player.Block
+	x, y := g.guardPoint()
+	direction := blowDirection(sourceX, g.positionX)
+
+	if g.blockTimer <= block.ParryWindow {
+		g.critTimer = block.CritWindow
+		g.events.Emit(CombatEvent{Kind: EventParry, X: x, Y: y, Direction: direction})
+		return true
+	}
+
+	damage *= block.DamageMultiplier
+	knockback *= block.KnockbackMultiplier
+	if damage >= g.playerHealth {
+		// Chip damage can still finish the player off. That's reported as the
+		// killing hit alone, so the damage isn't shown twice.
+		g.damagePlayer(damage, knockback, sourceX)
+		return false
+	}
+
+	// A blocked hit doesn't stagger, the player just loses a little health and ground
+	g.events.Emit(CombatEvent{Kind: EventBlock, X: x, Y: y, Direction: direction, Amount: damage})
+	g.playerHealth -= damage
+	if g.positionX < sourceX {
+		g.positionX -= knockback
+	} else {
+		g.positionX += knockback
+	}
+	g.positionX = g.level.ClampX(g.positionX)
+	return false
+}
+
+// guardPoint returns where blocked hits meet the player's guard, in world
+// coordinates: the front edge of the player's body at chest height
+func (g *Game) guardPoint() (x, y float64) {
+	bodyX, bodyY, bodyW, bodyH := playerBodyBounds(g.tuning, g.positionX, g.positionY)
+	x = bodyX + bodyW
+	if g.facingLeft {
+		x = bodyX
+	}
+	return x, bodyY + bodyH*0.3
+}
+
+// criticalDamage scales damage dealt during the critical-hit window after a parry
+func (g *Game) criticalDamage(damage int) int {
+	if g.critTimer <= 0 {
+		return damage
+	}
+	return int(math.Round(float64(damage) * g.tuning.Player.Block.CritMultiplier))
+}
+
+// guardSparkDuration is how long the spark of a block or parry stays on screen
+const guardSparkDuration = 0.2
+
+// playGuardSound reacts to blocks and parries with sound. There are no
+// dedicated clips yet, so the existing hit and swing sounds stand in.
+func (g *Game) playGuardSound(event CombatEvent) {
+	switch event.Kind {
+	case EventBlock:
+		if g.orcHitPlayer != nil {
+			g.orcHitPlayer.Rewind()
+			g.orcHitPlayer.Play()
+		}
+	case EventParry:
+		if g.attackPlayer != nil {
+			g.attackPlayer.Rewind()
+			g.attackPlayer.Play()
+		}
+		if g.orcHitPlayer != nil {
+			g.orcHitPlayer.Rewind()
+			g.orcHitPlayer.Play()
+		}
+	}
+}
+
+// showGuardSpark reacts to blocks and parries with a spark where the hit met the guard
+func (g *Game) showGuardSpark(event CombatEvent) {
+	switch event.Kind {
+	case EventBlock, EventParry:
+		g.guardSpark = event
+		g.guardSparkTimer = guardSparkDuration
+	}
+}
+
+// drawGuardSpark draws the spark of the latest block or parry as an expanding
+// cross; parries get a bigger, golden one
+func (g *Game) drawGuardSpark(screen *ebiten.Image) {
+	if g.guardSparkTimer <= 0 {
+		return
+	}
+	progress := 1 - g.guardSparkTimer/guardSparkDuration
+
+	size := 30 + 30*progress
+	c := color.RGBA{200, 220, 255, 255}
+	if g.guardSpark.Kind == EventParry {
+		size *= 2
+		c = color.RGBA{255, 220, 80, 255}
+	}
+	if g.settings.Accessibility.ReduceFlashing {
+		size *= 0.5
+	}
+	size *= g.camera.Zoom
+	alpha := 1 - progress
+	c = color.RGBA{uint8(float64(c.R) * alpha), uint8(float64(c.G) * alpha), uint8(float64(c.B) * alpha), uint8(255 * alpha)}
+
+	x, y := g.camera.WorldToScreen(g.guardSpark.X, g.guardSpark.Y)
+	const thickness = 4.0
+	g.fillRect(screen, x-size/2, y-thickness/2, size, thickness, c)
+	g.fillRect(screen, x-thickness/2, y-size/2, thickness, size, c)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"log"
+	"os"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2/audio/mp3"
+)
+
+// startBoss makes a freshly spawned boss the current boss fight
+func (g *Game) startBoss(boss *Orc) {
+	g.boss = boss
+	g.bossPhase = boss.BossPhase()
+	g.director.Announce(fmt.Sprintf("%s approaches!", boss.def.Name))
+	g.startBossMusic(boss.def.Boss.Music)
+	g.camera.ZoomTo(g.tuning.Camera.BossZoom) // Pull back to fit the bigger fight
+}
+
+// updateBoss announces boss phase changes and ends the fight once the boss dies
+func (g *Game) updateBoss() {
+	if g.boss == nil {
+		return
+	}
+
+	if !g.boss.IsAlive() {
+		g.director.Announce(fmt.Sprintf("%s defeated!", g.boss.def.Name))
+		g.stopBossMusic()
+		g.camera.ZoomTo(g.tuning.Camera.Zoom)
+		g.boss = nil
+		return
+	}
+
+	if phase := g.boss.BossPhase(); phase != g.bossPhase {
+		g.bossPhase = phase
+		if announcement := g.boss.def.Boss.Phases[phase].Announcement; announcement != "" {
+			g.director.Announce(announcement)
+		}
+	}
+}
+
+// loadMusic loads an mp3 file as a looping music player
+func (g *Game) loadMusic(path string) (*audio.Player, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", path, err)
+	}
+
+	stream, err := mp3.DecodeWithoutResampling(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	player, err := g.audioContext.NewPlayer(audio.NewInfiniteLoop(stream, stream.Length()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create player for %s: %w", path, err)
+	}
+	return player, nil
+}
+
+// preloadBossMusic loads every boss theme up front so a boss entrance doesn't stall
+// on decoding. A missing theme isn't fatal: that boss keeps the regular soundtrack.
+func (g *Game) preloadBossMusic(definitions map[string]*EnemyDefinition) {
+	g.bossMusic = map[string]*audio.Player{}
+	for _, def := range definitions {
+		if def.Boss == nil || def.Boss.Music == "" {
+			continue
+		}
+		if _, ok := g.bossMusic[def.Boss.Music]; ok {
+			continue
+		}
+		player, err := g.loadMusic(def.Boss.Music)
+		if err != nil {
+			log.Printf("Boss music unavailable, %s will use the soundtrack: %v", def.Name, err)
+			continue
+		}
+		g.bossMusic[def.Boss.Music] = player
+	}
+}
+
+// startBossMusic swaps the soundtrack for a boss theme
+func (g *Game) startBossMusic(path string) {
+	player, ok := g.bossMusic[path]
+	if !ok {
+		return
+	}
+
+	g.stopBossMusic()
+	if g.musicPlayer != nil {
+		g.musicPlayer.Pause()
+	}
+	player.SetVolume(g.settings.Audio.EffectiveMusicVolume())
+	player.Rewind()
+	player.Play()
+	g.activeBossMusic = player
+}
+
+// stopBossMusic stops the boss theme, if one is playing, and resumes the soundtrack
+func (g *Game) stopBossMusic() {
+	if g.activeBossMusic == nil {
+		return
+	}
+	g.activeBossMusic.Pause()
+	g.activeBossMusic = nil
+	if g.musicPlayer != nil {
+		g.musicPlayer.Play()
+	}
+}
+
+// fillRect draws a solid rectangle by scaling the shared 1x1 white pixel
+func (g *Game) fillRect(screen *ebiten.Image, x, y, width, height float64, c color.RGBA) {
+	if width <= 0 || height <= 0 {
+		return
+	}
+	opts := &ebiten.DrawImageOptions{}
+	opts.GeoM.Scale(width, height)
+	opts.GeoM.Translate(x, y)
+	opts.ColorScale.ScaleWithColor(c)
+	screen.DrawImage(g.pixel, opts)
+}
@@ -0,0 +1,84 @@
+package main
+
+// BuffStat is a player stat a buff can modify
+type BuffStat int
+
+const (
+	BuffDamage       BuffStat = iota // Multiplies damage dealt
+	BuffSpeed                        // Multiplies walking speed
+	BuffInvulnerable                 // Nothing can hurt the player
+)
+
+// buffStats names the stats in data files
+var buffStats = map[string]BuffStat{
+	"damage":       BuffDamage,
+	"speed":        BuffSpeed,
+	"invulnerable": BuffInvulnerable,
+}
+
+// Buff is a timed modifier on one of the player's stats
+type Buff struct {
+	Source     string // Pickup type that granted it; collecting the same type again refreshes it
+	Icon       string // Icon tag shown in the HUD
+	Stat       BuffStat
+	Multiplier float64 // Unused by BuffInvulnerable
+	Duration   float64 // Seconds the buff lasts in total
+	Remaining  float64 // Seconds left
+}
+
+// BuffManager keeps the player's active buffs and combines their effects.
+// Buffs from different sources stack by multiplying together.
+type BuffManager struct {
+	buffs []Buff
+}
+
+// Add starts a buff, or restarts it if one from the same source is already running
+func (m *BuffManager) Add(buff Buff) {
+	buff.Remaining = buff.Duration
+	for i := range m.buffs {
+		if m.buffs[i].Source == buff.Source {
+			m.buffs[i] = buff
+			return
+		}
+	}
+	m.buffs = append(m.buffs, buff)
+}
+
+// Update counts buffs down and removes the ones that ran out
+func (m *BuffManager) Update() {
+	const dt = 1.0 / 60.0 // Assuming 60 FPS
+	active := m.buffs[:0]
+	for _, buff := range m.buffs {
+		buff.Remaining -= dt
+		if buff.Remaining > 0 {
+			active = append(active, buff)
+		}
+	}
+	m.buffs = active
+}
+
+// Multiplier returns the combined multiplier of every buff on a stat, 1 if there are none
+func (m *BuffManager) Multiplier(stat BuffStat) float64 {
+	multiplier := 1.0
+	for _, buff := range m.buffs {
+		if buff.Stat == stat {
+			multiplier *= buff.Multiplier
+		}
+	}
+	return multiplier
+}
+
+// Has reports whether any buff on a stat is active
+func (m *BuffManager) Has(stat BuffStat) bool {
+	for _, buff := range m.buffs {
+		if buff.Stat == stat {
+			return true
+		}
+	}
+	return false
+}
+
+// Active returns the running buffs, oldest first. The slice is only valid until the next Update.
+func (m *BuffManager) Active() []Buff {
+	return m.buffs
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Camera decides which part of the world is on screen. World positions are in
+// pixels, with x = 0 at the center of the level and y = 0 level with the middle
+// of the screen. Everything drawn in the world goes through the camera, so this
+// is the one place world coordinates become screen coordinates.
+type Camera struct {
+	X, Y float64 // World position shown at the center of the screen
+	Zoom float64
+
+	targetZoom float64
+	tuning     *Tuning
+	level      *Level
+
+	// Shake is a visual offset only; it never moves X and Y
+	trauma     float64 // 0 to 1; the shake grows with its square, so small knocks stay subtle
+	shakeClock float64 // Drives the shake pattern
+}
+
+// NewCamera creates a camera centered on the given world position
+func NewCamera(tuning *Tuning, level *Level, x, y float64) *Camera {
+	c := &Camera{
+		X:          x,
+		Y:          y,
+		Zoom:       tuning.Camera.Zoom,
+		targetZoom: tuning.Camera.Zoom,
+		tuning:     tuning,
+		level:      level,
+	}
+	c.clamp()
+	return c
+}
+
+// Update moves the camera towards the target, once it leaves the deadzone
+// around the center of the screen, and advances zoom
+func (c *Camera) Update(targetX, targetY float64) {
+	tuning := c.tuning.Camera
+
+	c.Zoom += (c.targetZoom - c.Zoom) * tuning.ZoomSpeed
+
+	// The deadzone is measured on screen, so it covers less of the world when zoomed in
+	c.X += deadzoneOffset(targetX-c.X, tuning.Deadzone[0]/c.Zoom) * tuning.Smoothing
+	c.Y += deadzoneOffset(targetY-c.Y, tuning.Deadzone[1]/c.Zoom) * tuning.Smoothing
+	c.clamp()
+}
+
+// UpdateShake wears off the shake. It's called every frame, even while
+// hit-stop holds the rest of the game, so a freeze can still shake.
+func (c *Camera) UpdateShake() {
+	const dt = 1.0 / 60.0 // Assuming 60 FPS
+	if c.trauma > 0 {
+		c.trauma = math.Max(0, c.trauma-c.tuning.Camera.TraumaDecay*dt)
+		c.shakeClock += dt
+	}
+}
+
+// deadzoneOffset returns how far the camera has to move so a target offset
+// from its center by distance is back inside a deadzone of the given half-size
+func deadzoneOffset(distance, halfSize float64) float64 {
+	switch {
+	case distance > halfSize:
+		return distance - halfSize
+	case distance < -halfSize:
+		return distance + halfSize
+	}
+	return 0
+}
+
+// clamp keeps the view inside the level. A level narrower than the view
+// (possible when zoomed out) stays centered.
+func (c *Camera) clamp() {
+	halfViewW := float64(screenWidth) / 2 / c.Zoom
+	halfViewH := float64(screenHeight) / 2 / c.Zoom
+	c.X = clampView(c.X, -c.level.Width/2, c.level.Width/2, halfViewW)
+	c.Y = clampView(c.Y, -float64(screenHeight)/2, float64(screenHeight)/2, halfViewH)
+}
+
+// clampView keeps a view of the given half-size centered at center between min and max
+func clampView(center, min, max, halfView float64) float64 {
+	if max-min <= 2*halfView {
+		return (min + max) / 2
+	}
+	return math.Max(min+halfView, math.Min(max-halfView, center))
+}
+
+// ZoomTo eases the camera to a new zoom level; 1 shows the world at its real size
+func (c *Camera) ZoomTo(zoom float64) {
+	c.targetZoom = zoom
+}
+
+// AddTrauma shakes the view harder. Trauma adds up to a maximum of 1, so
+// hits in quick succession build on each other.
+func (c *Camera) AddTrauma(amount float64) {
+	c.trauma = math.Min(1, c.trauma+amount)
+}
+
+// shakeOffset returns the current shake offset in screen pixels. Two sine waves
+// at unrelated frequencies give a jittery pattern without using the game's RNG.
+func (c *Camera) shakeOffset() (x, y float64) {
+	if c.trauma <= 0 {
+		return 0, 0
+	}
+	amount := c.tuning.Camera.MaxShake * c.trauma * c.trauma
+	return amount * math.Sin(c.shakeClock*71) * math.Cos(c.shakeClock*23),
+		amount * math.Sin(c.shakeClock*59+1) * math.Cos(c.shakeClock*31)
+}
+
+// WorldToScreen converts a world position to a screen position
+func (c *Camera) WorldToScreen(x, y float64) (screenX, screenY float64) {
+	shakeX, shakeY := c.shakeOffset()
+	return (x-c.X)*c.Zoom + float64(screenWidth)/2 + shakeX,
+		(y-c.Y)*c.Zoom + float64(screenHeight)/2 + shakeY
+}
+
+// Apply adds the camera transform to geo, which should already place what's
+// being drawn at its world position
+func (c *Camera) Apply(geo *ebiten.GeoM) {
+	shakeX, shakeY := c.shakeOffset()
+	geo.Translate(-c.X, -c.Y)
+	geo.Scale(c.Zoom, c.Zoom)
+	geo.Translate(float64(screenWidth)/2+shakeX, float64(screenHeight)/2+shakeY)
+}
+
+// DrawSprite draws a sprite scaled up by scale and centered on a world position,
+// mirrored if flip is set. opts may carry color changes; its GeoM is overwritten.
+func (c *Camera) DrawSprite(screen, sprite *ebiten.Image, x, y, scale float64, flip bool, opts *ebiten.DrawImageOptions) {
+	opts.GeoM = c.spriteGeoM(sprite, x, y, scale, flip)
+	screen.DrawImage(sprite, opts)
+}
+
+// DrawFlashingSprite draws a sprite like DrawSprite, turned towards white by
+// amount (0 to 1) with the flash shader
+func (c *Camera) DrawFlashingSprite(screen, sprite *ebiten.Image, x, y, scale float64, flip bool, opts *ebiten.DrawImageOptions, flash *ebiten.Shader, amount float64) {
+	bounds := sprite.Bounds()
+	shaderOpts := &ebiten.DrawRectShaderOptions{
+		GeoM:       c.spriteGeoM(sprite, x, y, scale, flip),
+		ColorScale: opts.ColorScale,
+		Images:     [4]*ebiten.Image{sprite},
+		Uniforms:   map[string]any{"Amount": float32(min(1, amount))},
+	}
+	screen.DrawRectShader(bounds.Dx(), bounds.Dy(), flash, shaderOpts)
+}
+
+// spriteGeoM returns the transform that draws a sprite scaled up by scale and
+// centered on a world position, mirrored if flip is set
+func (c *Camera) spriteGeoM(sprite *ebiten.Image, x, y, scale float64, flip bool) ebiten.GeoM {
+	bounds := sprite.Bounds()
+	var geo ebiten.GeoM
+	geo.Translate(-float64(bounds.Dx())/2, -float64(bounds.Dy())/2)
+	if flip {
+		geo.Scale(-scale, scale)
+	} else {
+		geo.Scale(scale, scale)
+	}
+	geo.Translate(x, y)
+	c.Apply(&geo)
+	return geo
+}
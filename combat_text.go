@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// FloatingNumberStyle picks how a floating number looks
+type FloatingNumberStyle int
+
+const (
+	NumberDamage   FloatingNumberStyle = iota // Damage the player dealt
+	NumberCritical                            // A critical hit the player dealt
+	NumberHurt                                // Damage the player took
+	NumberHeal                                // Health the player regained
+	NumberPickup                              // Name of a pickup the player collected
+)
+
+// floatingNumberColors is the color of each style
+var floatingNumberColors = map[FloatingNumberStyle]color.RGBA{
+	NumberDamage:   {255, 240, 200, 255},
+	NumberCritical: {255, 210, 40, 255},
+	NumberHurt:     {255, 70, 70, 255},
+	NumberHeal:     {90, 230, 90, 255},
+	NumberPickup:   {140, 200, 255, 255},
+}
+
+// numberHeadroom is how far above a fighter's center its numbers appear, in world pixels
+const numberHeadroom = 80.0
+
+// FloatingNumber is a number rising out of a fighter. Positions are in world coordinates.
+type FloatingNumber struct {
+	X, Y  float64 // Where it started
+	Text  string
+	Style FloatingNumberStyle
+	Age   float64
+}
+
+// KillFeedEntry is one line of the kill feed
+type KillFeedEntry struct {
+	Text string
+	Age  float64
+}
+
+// CombatText shows what combat is doing in words and numbers: floating damage
+// and heal numbers in the world, plus a kill feed and kill streak announcements
+// on the HUD. It only reacts to combat events, so it never affects the game.
+type CombatText struct {
+	tuning  *Tuning
+	fonts   *Fonts
+	opts    ebiten.DrawImageOptions // Reused for every line drawn
+	numbers []FloatingNumber
+	feed    []KillFeedEntry // Newest first
+
+	streak      int     // Kills in the current streak
+	streakTimer float64 // Time left to extend the streak
+	streakName  string  // Announcement on screen, "" if none
+	streakAge   float64
+}
+
+// NewCombatText creates the combat text layer
+func NewCombatText(tuning *Tuning, fonts *Fonts) *CombatText {
+	return &CombatText{tuning: tuning, fonts: fonts}
+}
+
+// OnCombatEvent adds numbers and kills as they happen. It's a CombatEvents listener.
+func (c *CombatText) OnCombatEvent(event CombatEvent) {
+	switch event.Kind {
+	case EventEnemyHit, EventEnemyKilled:
+		style := NumberDamage
+		label := fmt.Sprintf("%.0f", event.Amount)
+		if event.Critical {
+			style = NumberCritical
+			label += "!"
+		}
+		c.addNumber(event.X+event.Direction*20, event.Y-numberHeadroom, label, style)
+		if event.Kind == EventEnemyKilled {
+			c.addKill(event.Name)
+		}
+	case EventPlayerHit, EventBlock:
+		if event.Amount > 0 {
+			c.addNumber(event.X, event.Y-numberHeadroom, fmt.Sprintf("-%.0f", event.Amount), NumberHurt)
+		}
+	case EventPlayerHealed:
+		c.addNumber(event.X, event.Y-numberHeadroom, fmt.Sprintf("+%.0f", event.Amount), NumberHeal)
+	case EventPickup:
+		c.addNumber(event.X, event.Y-numberHeadroom, event.Name, NumberPickup)
+	}
+}
+
+// addNumber starts a floating number, reusing the slot of an expired one if there is one
+func (c *CombatText) addNumber(x, y float64, label string, style FloatingNumberStyle) {
+	number := FloatingNumber{X: x, Y: y, Text: label, Style: style}
+	for i := range c.numbers {
+		if c.numbers[i].Age >= c.tuning.Text.NumberDuration {
+			c.numbers[i] = number
+			return
+		}
+	}
+	c.numbers = append(c.numbers, number)
+}
+
+// addKill puts a kill at the top of the feed and extends the kill streak
+func (c *CombatText) addKill(name string) {
+	textTuning := c.tuning.Text
+
+	c.feed = append([]KillFeedEntry{{Text: name + " slain"}}, c.feed...)
+	if len(c.feed) > textTuning.FeedLines {
+		c.feed = c.feed[:textTuning.FeedLines]
+	}
+
+	if c.streakTimer > 0 {
+		c.streak++
+	} else {
+		c.streak = 1
+	}
+	c.streakTimer = textTuning.StreakWindow
+
+	// Announce a streak the moment it reaches one of the thresholds
+	for _, streak := range textTuning.Streaks {
+		if streak.Kills == c.streak {
+			c.streakName = streak.Name
+			c.streakAge = 0
+		}
+	}
+}
+
+// Update ages numbers, feed lines and the streak
+func (c *CombatText) Update() {
+	const dt = 1.0 / 60.0 // Assuming 60 FPS
+	for i := range c.numbers {
+		c.numbers[i].Age += dt
+	}
+
+	for i := range c.feed {
+		c.feed[i].Age += dt
+	}
+	// Entries are oldest at the end, so expired ones can be cut off there
+	for len(c.feed) > 0 && c.feed[len(c.feed)-1].Age >= c.tuning.Text.FeedDuration {
+		c.feed = c.feed[:len(c.feed)-1]
+	}
+
+	if c.streakTimer > 0 {
+		c.streakTimer -= dt
+	}
+	if c.streakName != "" {
+		c.streakAge += dt
+		if c.streakAge >= c.tuning.Text.StreakDuration {
+			c.streakName = ""
+		}
+	}
+}
+
+// DrawNumbers draws the floating numbers through the camera. They rise and
+// ease to a stop, fading over the second half of their life; critical hits
+// are bigger and pop in.
+func (c *CombatText) DrawNumbers(screen *ebiten.Image, camera *Camera) {
+	textTuning := c.tuning.Text
+	for _, number := range c.numbers {
+		life := number.Age / textTuning.NumberDuration
+		if life >= 1 {
+			continue
+		}
+
+		rise := textTuning.NumberRise * (1 - (1-life)*(1-life))
+		x, y := camera.WorldToScreen(number.X, number.Y-rise)
+
+		scale := textTuning.NumberScale
+		if number.Style == NumberCritical {
+			scale = textTuning.CritScale * (1 + 0.5*math.Max(0, 1-life*8)) // Starts larger and settles
+		}
+		scale *= camera.Zoom
+
+		alpha := math.Min(1, 2*(1-life))
+		c.opts.ColorScale.Reset()
+		c.opts.ColorScale.ScaleWithColor(floatingNumberColors[number.Style])
+		c.opts.ColorScale.ScaleAlpha(float32(alpha))
+		drawCenteredText(screen, c.fonts.Numbers, number.Text, x, y, scale, &c.opts)
+	}
+}
+
+// DrawHUD draws the kill feed in the top right corner and any kill streak
+// announcement below the wave announcements
+func (c *CombatText) DrawHUD(screen *ebiten.Image) {
+	textTuning := c.tuning.Text
+	_, lineHeight := c.fonts.HUD.Measure("0")
+	for i, entry := range c.feed {
+		// Fade out over the last second
+		alpha := math.Min(1, textTuning.FeedDuration-entry.Age)
+		layout := Layout{Anchor: AnchorTopRight, X: 20, Y: 20 + float64(i)*(lineHeight+4)}
+		x, y := layout.Place(c.fonts.HUD.Measure(entry.Text))
+		c.opts.GeoM.Reset()
+		c.opts.GeoM.Translate(x, y)
+		c.opts.ColorScale.Reset()
+		c.opts.ColorScale.ScaleWithColor(color.RGBA{230, 230, 230, 255})
+		c.opts.ColorScale.ScaleAlpha(float32(alpha))
+		c.fonts.HUD.Draw(screen, entry.Text, &c.opts)
+	}
+
+	if c.streakName != "" {
+		// Pop in, then fade over the last third
+		life := c.streakAge / textTuning.StreakDuration
+		scale := 1 + 0.4*math.Max(0, 1-life*6)
+		alpha := math.Min(1, 3*(1-life))
+		c.opts.ColorScale.Reset()
+		c.opts.ColorScale.ScaleWithColor(color.RGBA{255, 120, 40, 255})
+		c.opts.ColorScale.ScaleAlpha(float32(alpha))
+		drawCenteredText(screen, c.fonts.Large, c.streakName+"!", screenWidth/2, screenHeight/4+100, scale, &c.opts)
+	}
+}
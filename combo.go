@@ -0,0 +1,123 @@
+package main
+
+import "math"
+
+// currentPlayerAttack returns the combo step being performed (or last performed)
+func (g *Game) currentPlayerAttack() *WeaponAttack {
+	combo := g.weapon().Combo
+	// Clamp in case a weapon reload shortened the combo mid-swing
+	return &combo[min(g.comboStep, len(combo)-1)]
+}
+
+// isFinalComboStep reports whether the current step is the combo finisher
+func (g *Game) isFinalComboStep() bool {
+	return g.comboStep >= len(g.weapon().Combo)-1
+}
+
+// attackFrames returns the frame range of the current combo step's animation
+func (g *Game) attackFrames() (start, end int) {
+	frames := g.playerTags[g.currentPlayerAttack().Tag]
+	return frames[0], frames[1]
+}
+
+// playerAttackActive reports whether the player's swing is on a frame that can hit
+func (g *Game) playerAttackActive() bool {
+	if !g.isAttacking {
+		return false
+	}
+	start, _ := g.attackFrames()
+	frame := g.currentFrame - start
+	active := g.currentPlayerAttack().ActiveFrames
+	return frame >= active[0] && frame <= active[1]
+}
+
+// startComboStep begins a step of the attack combo
+func (g *Game) startComboStep(step int) {
+	g.comboStep = step
+	g.comboQueued = false
+	g.comboTimer = 0
+	g.swingID++ // A new swing can hit every enemy again
+
+	g.isAttacking = true
+	g.currentFrame, _ = g.attackFrames()
+	g.frameTimer = 0
+	g.events.Emit(CombatEvent{Kind: EventSwing, X: g.positionX, Y: g.positionY, Direction: facingDirection(g.facingLeft)})
+
+	// Play the weapon's attack sound effect
+	g.playAttackSound()
+}
+
+// handleAttackPress starts or continues the combo when attack is pressed.
+// Presses during a swing queue the next step; presses shortly after one continue it.
+func (g *Game) handleAttackPress() {
+	if !g.isAttacking {
+		next := 0
+		if g.comboTimer > 0 {
+			next = g.comboStep + 1
+		}
+		g.startComboStep(next)
+	} else if !g.isFinalComboStep() {
+		g.comboQueued = true
+	}
+}
+
+// finishSwing is called when a swing's animation ends. It chains straight into
+// the next step if one was queued, otherwise it opens the window for the next press.
+func (g *Game) finishSwing() {
+	if g.comboQueued && !g.isFinalComboStep() {
+		g.startComboStep(g.comboStep + 1)
+		return
+	}
+
+	g.isAttacking = false
+	g.comboQueued = false
+	if !g.isFinalComboStep() {
+		g.comboTimer = g.tuning.Player.ComboWindow
+	}
+	if g.isWalking {
+		g.currentFrame = g.walkFrameStart
+	} else {
+		g.currentFrame = g.idleFrameStart
+	}
+}
+
+// updateComboTimers counts down the window for continuing the combo
+// and the time left before the hit counter resets
+func (g *Game) updateComboTimers() {
+	const dt = 1.0 / 60.0 // Assuming 60 FPS
+	g.comboTimer = math.Max(0, g.comboTimer-dt)
+	if g.comboHitTimer > 0 {
+		g.comboHitTimer -= dt
+		if g.comboHitTimer <= 0 {
+			g.comboHits = 0
+		}
+	}
+}
+
+// registerComboHit counts a landed hit for the HUD hit counter
+func (g *Game) registerComboHit() {
+	g.comboHits++
+	g.comboHitTimer = g.tuning.Player.ComboTimeout
+}
+
+// breakCombo ends the combo, e.g. when the player is hit
+func (g *Game) breakCombo() {
+	g.comboQueued = false
+	g.comboTimer = 0
+	g.comboHits = 0
+	g.comboHitTimer = 0
+}
+
+// playerHit returns the hit the current combo step deals, with upgrades and any damage buff applied
+func (g *Game) playerHit() Hit {
+	attack := g.currentPlayerAttack()
+	base := attack.Damage + int(math.Round(g.stats.Damage))
+	damage := int(math.Round(float64(g.criticalDamage(base)) * g.buffs.Multiplier(BuffDamage)))
+	return Hit{
+		SourceX:   g.positionX,
+		Damage:    damage,
+		Knockback: attack.Knockback * g.stats.Knockback,
+		Knockdown: attack.Knockdown,
+		Critical:  g.critTimer > 0,
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// crowdStiffness is the fraction of an overlap resolved per tick. Resolving
+// only part of it lets crowds settle smoothly instead of snapping apart.
+const crowdStiffness = 0.5
+
+// CrowdBody is one enemy's collision body for the separation pass
+type CrowdBody struct {
+	Index     int     // Caller's index for the enemy, used to write results back
+	X         float64 // Center position
+	Y         float64 // Depth in the arena
+	HalfWidth float64
+	Mass      float64 // Heavier bodies get pushed less
+	VelocityX float64 // Knockback velocity, shared with bodies it slides into
+}
+
+// SeparateCrowd pushes overlapping bodies apart and passes knockback on to the
+// bodies a sliding enemy crashes into. Bodies are sorted by X and swept, so only
+// neighbours that can actually touch are compared, which keeps large crowds cheap.
+// Bodies more than depth apart in Y are on different lines of the arena and pass
+// each other freely. Bodies are only ever pushed along X.
+// The slice is reordered; use Index to map results back.
+func SeparateCrowd(bodies []CrowdBody, depth float64) {
+	slices.SortFunc(bodies, func(a, b CrowdBody) int {
+		// Break ties by index so the result doesn't depend on the sort algorithm
+		return cmp.Or(cmp.Compare(a.X, b.X), cmp.Compare(a.Index, b.Index))
+	})
+
+	maxHalfWidth := 0.0
+	for _, body := range bodies {
+		maxHalfWidth = max(maxHalfWidth, body.HalfWidth)
+	}
+
+	for i := range bodies {
+		a := &bodies[i]
+		// Nothing further right than this can reach a
+		for j := i + 1; j < len(bodies) && bodies[j].X-a.X < a.HalfWidth+maxHalfWidth; j++ {
+			b := &bodies[j]
+			overlap := a.HalfWidth + b.HalfWidth - (b.X - a.X)
+			if overlap <= 0 || math.Abs(a.Y-b.Y) >= depth {
+				continue
+			}
+
+			// Push both apart, the lighter one further (a is on the left)
+			totalMass := a.Mass + b.Mass
+			push := overlap * crowdStiffness
+			a.X -= push * b.Mass / totalMass
+			b.X += push * a.Mass / totalMass
+
+			// If they're sliding into each other, they move on together (inelastic collision)
+			if a.VelocityX > b.VelocityX {
+				shared := (a.Mass*a.VelocityX + b.Mass*b.VelocityX) / totalMass
+				a.VelocityX = shared
+				b.VelocityX = shared
+			}
+		}
+	}
+}
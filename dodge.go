@@ -0,0 +1,116 @@
+package main
+
+import "math"
+
+// dodgeFrames returns the frame range of the dodge roll animation
+func (g *Game) dodgeFrames() (start, end int) {
+	frames := g.playerTags[g.tuning.Player.Dodge.Tag]
+	return frames[0], frames[1]
+}
+
+// hasPlayerTag reports whether the player's sprite has an animation tag
+func (g *Game) hasPlayerTag(tag string) bool {
+	_, ok := g.playerTags[tag]
+	return ok
+}
+
+// hasPlayerLayer reports whether the player's sprite has a layer
+func (g *Game) hasPlayerLayer(layer string) bool {
+	return g.asepriteFile.LayerIndex(layer) >= 0
+}
+
+// canDodge reports whether the player is able to start a roll right now
+func (g *Game) canDodge() bool {
+	return g.playerState == PlayerStateAlive && !g.isDodging &&
+		g.dodgeCooldown <= 0 && g.stamina >= g.tuning.Player.Dodge.StaminaCost
+}
+
+// startDodge rolls in the held direction, or the way the player faces if none is held.
+// Rolling cancels an attack in progress.
+func (g *Game) startDodge() {
+	dodge := g.tuning.Player.Dodge
+
+	switch {
+	case g.input.IsHeld(ActionMoveLeft) && !g.input.IsHeld(ActionMoveRight):
+		g.facingLeft = true
+	case g.input.IsHeld(ActionMoveRight) && !g.input.IsHeld(ActionMoveLeft):
+		g.facingLeft = false
+	}
+
+	g.isDodging = true
+	g.dodgeTimer = 0
+	g.isAttacking = false
+	g.comboQueued = false
+	g.isWalking = false
+	g.isBlocking = false
+
+	g.stamina -= dodge.StaminaCost
+	g.staminaDelay = g.tuning.Player.Stamina.RegenDelay
+
+	g.currentFrame, _ = g.dodgeFrames()
+	g.frameTimer = 0
+}
+
+// updateDodge moves the player through a roll and regenerates stamina
+func (g *Game) updateDodge() {
+	const dt = 1.0 / 60.0 // Assuming 60 FPS
+	dodge := g.tuning.Player.Dodge
+	stamina := g.tuning.Player.Stamina
+
+	if g.invulnTimer > 0 {
+		g.invulnTimer -= dt
+	}
+	if g.dodgeCooldown > 0 {
+		g.dodgeCooldown -= dt
+	}
+
+	// Stamina comes back after a short delay
+	if g.staminaDelay > 0 {
+		g.staminaDelay -= dt
+	} else {
+		g.stamina = math.Min(stamina.Max, g.stamina+stamina.RegenPerSecond*dt)
+	}
+
+	if !g.isDodging {
+		return
+	}
+
+	g.dodgeTimer += dt
+	if g.facingLeft {
+		g.positionX -= dodge.Speed
+	} else {
+		g.positionX += dodge.Speed
+	}
+	// Keep within the level
+	g.positionX = g.level.ClampX(g.positionX)
+
+	if g.dodgeTimer >= dodge.Duration {
+		g.isDodging = false
+		g.dodgeCooldown = dodge.Cooldown
+		g.currentFrame = g.idleFrameStart
+		g.frameTimer = 0
+	}
+}
+
+// dodgeFrameDuration returns how long each roll frame shows so the
+// animation spans the whole roll
+func (g *Game) dodgeFrameDuration() float64 {
+	start, end := g.dodgeFrames()
+	return g.tuning.Player.Dodge.Duration / float64(end-start+1)
+}
+
+// isInvulnerable reports whether the player is in a dodge's invulnerability
+// window or still recovering from a hit
+func (g *Game) isInvulnerable() bool {
+	if g.invulnTimer > 0 || g.buffs.Has(BuffInvulnerable) {
+		return true
+	}
+	window := g.tuning.Player.Dodge.Invulnerable
+	return g.isDodging && g.dodgeTimer >= window[0] && g.dodgeTimer <= window[1]
+}
+
+// canBeHit reports whether attacks can currently damage the player
+func (g *Game) canBeHit() bool {
+	alive := g.playerState == PlayerStateAlive || g.playerState == PlayerStateHurt
+	return alive && !g.isInvulnerable()
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+
+	"rpg_demo/aseprite"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// enemiesPath is the location of the enemy definition file
+const enemiesPath = "assets/enemies.json"
+
+// EnemyDefinition describes an enemy archetype. Every enemy in the game is
+// an Orc driven by one of these, so new archetypes only need new data.
+type EnemyDefinition struct {
+	Name       string     `json:"name"`   // Display name
+	Sprite     string     `json:"sprite"` // Path to the .aseprite file
+	Tags       EnemyTags  `json:"tags"`   // Names of the animation tags in the sprite
+	Scale      float64    `json:"scale"`  // How much the sprite is scaled up when drawn
+	Tint       [3]float64 `json:"tint"`   // RGB multipliers applied to the sprite (1, 1, 1 = unchanged)
+	ScoreValue int        `json:"score"`  // Points awarded for a kill
+	XP         int        `json:"xp"`     // Experience awarded for a kill
+
+	Health              int     `json:"health"`
+	WalkSpeed           float64 `json:"walkSpeed"`           // Pixels per tick, before wave scaling
+	BodySize            float64 `json:"bodySize"`            // Collision box size in sprite pixels
+	KnockbackResistance float64 `json:"knockbackResistance"` // 0 takes full knockback, 1 is immovable
+	HurtDuration        float64 `json:"hurtDuration"`        // Seconds the enemy is stunned after a hit
+	ShieldHits          int     `json:"shieldHits"`          // Frontal hits blocked before the shield breaks
+
+	Behavior string `json:"behavior"` // AI behavior, see enemyBehaviors
+
+	// Attacks: every HeavyEvery-th swing uses the slower, harder-hitting Attack02
+	HeavyEvery int         `json:"heavyEvery"`
+	Attack01   EnemyAttack `json:"attack01"`
+	Attack02   EnemyAttack `json:"attack02"`
+
+	Projectile *EnemyProjectile `json:"projectile,omitempty"` // If set, attacks throw this instead of striking in melee
+	Boss       *BossDefinition  `json:"boss,omitempty"`       // Only set for bosses
+}
+
+// EnemyProjectile describes what a ranged enemy throws. Damage and knockback come
+// from the attack that throws it, and Reach is how far the enemy throws from.
+type EnemyProjectile struct {
+	Speed    float64    `json:"speed"`    // Horizontal pixels per tick
+	Gravity  float64    `json:"gravity"`  // Pixels per tick per tick; above 0 lobs the projectile at the player
+	Lifetime float64    `json:"lifetime"` // Seconds before it disappears
+	Size     [2]float64 `json:"size"`     // Width and height in pixels
+	Spin     float64    `json:"spin"`     // Radians per tick
+	Color    [3]float64 `json:"color"`    // RGB, 0 to 1
+}
+
+// EnemyTags maps each enemy animation to a tag name in the sprite file
+type EnemyTags struct {
+	Idle     string `json:"idle"`
+	Walk     string `json:"walk"`
+	Attack01 string `json:"attack01"`
+	Attack02 string `json:"attack02"`
+	Hurt     string `json:"hurt"`
+	Death    string `json:"death"`
+}
+
+// EnemyAttack describes one enemy attack, played with the matching sprite tag
+type EnemyAttack struct {
+	Reach         float64 `json:"reach"`         // How far the hit reaches in front of the enemy, in sprite pixels
+	Damage        float64 `json:"damage"`        // Damage dealt to the player
+	Knockback     float64 `json:"knockback"`     // Pixels the player is pushed back
+	FrameDuration float64 `json:"frameDuration"` // Seconds per animation frame (slower means a longer wind-up)
+	ActiveFrames  [2]int  `json:"activeFrames"`  // First and last frame that can hit, relative to the tag start
+	Cooldown      float64 `json:"cooldown"`      // Seconds of recovery after the swing before the enemy moves again
+}
+
+// BossDefinition turns an enemy into a boss: it gets a bar at the top of the
+// screen, its own music, and phases with telegraphed special attacks
+type BossDefinition struct {
+	Music  string      `json:"music"`  // Path to the .mp3 played while the boss is alive
+	Phases []BossPhase `json:"phases"` // In order of decreasing health
+}
+
+// BossPhase is one stage of a boss fight
+type BossPhase struct {
+	HealthBelow     float64     `json:"healthBelow"`     // Fraction of max health at which the phase starts (1 for the first phase)
+	Announcement    string      `json:"announcement"`    // Shown when the phase starts, optional
+	SpeedMultiplier float64     `json:"speedMultiplier"` // Scales walk speed
+	Special         BossSpecial `json:"special"`
+}
+
+// BossSpecial is a telegraphed boss attack: the boss stops and glows for
+// Telegraph seconds before the swing, giving the player time to get away
+type BossSpecial struct {
+	EnemyAttack
+	Tag       string  `json:"tag"`       // Animation tag played for the attack
+	Telegraph float64 `json:"telegraph"` // Seconds of warning before the attack starts
+	Every     float64 `json:"every"`     // Seconds between special attacks
+}
+
+// enemyBehaviors maps the AI behavior names used in enemy definitions to
+// functions that build the behavior tree for an enemy of that type
+var enemyBehaviors = map[string]func(def *EnemyDefinition) Behavior{
+	// Crowd around the player in ranks, backing off briefly when badly hurt
+	"melee": func(def *EnemyDefinition) Behavior {
+		return Selector{
+			RetreatWhenHurt{Below: 0.34, Duration: 1.0, Speed: 1.2},
+			Surround{Spacing: 1.2},
+		}
+	},
+	// Hang back while the player is looking, dart in behind when they turn away
+	"skirmisher": func(def *EnemyDefinition) Behavior {
+		reach := def.Attack02.Reach * def.Scale
+		return Selector{
+			RetreatWhenHurt{Below: 1, Duration: 0.6, Speed: 1.5},
+			Sequence{
+				Condition(func(ctx *AIContext) bool {
+					return ctx.PlayerFacingLeft == (ctx.Self.X < ctx.PlayerX)
+				}),
+				KeepDistance{Min: reach * 1.5, Max: reach * 3},
+			},
+			Flank{},
+		}
+	},
+	// Stay at throwing distance and attack from there, backing off when hit
+	"ranged": func(def *EnemyDefinition) Behavior {
+		reach := math.Min(def.Attack01.Reach, def.Attack02.Reach) * def.Scale
+		return Selector{
+			RetreatWhenHurt{Below: 1, Duration: 0.8, Speed: 1.3},
+			KeepDistance{Min: reach * 0.5, Max: reach * 0.9, Attack: true},
+		}
+	},
+	// Charge straight at the player, ignoring the crowd
+	"brawler": func(def *EnemyDefinition) Behavior {
+		return Chase{}
+	},
+	// Patrol until the player comes close or lands a hit, then fight like "melee"
+	"guard": func(def *EnemyDefinition) Behavior {
+		return Selector{
+			Patrol{Aggro: 500},
+			Surround{Spacing: 1.2},
+		}
+	},
+}
+
+// LoadEnemyDefinitions reads and validates the enemy definition file
+func LoadEnemyDefinitions(path string) (map[string]*EnemyDefinition, error) {
+	var definitions map[string]*EnemyDefinition
+	validate := func() error {
+		if len(definitions) == 0 {
+			return errors.New("no enemies defined")
+		}
+		// Validate in name order so errors are reported consistently
+		for _, name := range sortedKeys(definitions) {
+			if err := definitions[name].Validate(); err != nil {
+				return fmt.Errorf("enemy %q: %w", name, err)
+			}
+		}
+		return nil
+	}
+	if err := decodeDataFile(path, "enemy", &definitions, validate); err != nil {
+		return nil, err
+	}
+	return definitions, nil
+}
+
+// Validate checks that every value is in a sensible range
+func (d *EnemyDefinition) Validate() error {
+	var errs []error
+	positive := func(name string, v float64) {
+		if v <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive, got %v", name, v))
+		}
+	}
+	nonNegative := func(name string, v float64) {
+		if v < 0 {
+			errs = append(errs, fmt.Errorf("%s cannot be negative, got %v", name, v))
+		}
+	}
+
+	if d.Sprite == "" {
+		errs = append(errs, errors.New("sprite is required"))
+	}
+	positive("scale", d.Scale)
+	for _, c := range d.Tint {
+		nonNegative("tint", c)
+	}
+	nonNegative("score", float64(d.ScoreValue))
+	nonNegative("xp", float64(d.XP))
+	positive("health", float64(d.Health))
+	positive("walkSpeed", d.WalkSpeed)
+	positive("bodySize", d.BodySize)
+	if d.KnockbackResistance < 0 || d.KnockbackResistance > 1 {
+		errs = append(errs, fmt.Errorf("knockbackResistance must be in [0, 1], got %v", d.KnockbackResistance))
+	}
+	nonNegative("hurtDuration", d.HurtDuration)
+	nonNegative("shieldHits", float64(d.ShieldHits))
+	if _, ok := enemyBehaviors[d.Behavior]; !ok {
+		errs = append(errs, fmt.Errorf("unknown behavior %q", d.Behavior))
+	}
+
+	positive("heavyEvery", float64(d.HeavyEvery))
+	type namedAttack struct {
+		name   string
+		attack EnemyAttack
+	}
+	attacks := []namedAttack{{"attack01", d.Attack01}, {"attack02", d.Attack02}}
+
+	if p := d.Projectile; p != nil {
+		positive("projectile.speed", p.Speed)
+		nonNegative("projectile.gravity", p.Gravity)
+		positive("projectile.lifetime", p.Lifetime)
+		positive("projectile.size[0]", p.Size[0])
+		positive("projectile.size[1]", p.Size[1])
+		for _, c := range p.Color {
+			nonNegative("projectile.color", c)
+		}
+	}
+
+	if d.Boss != nil {
+		if len(d.Boss.Phases) == 0 {
+			errs = append(errs, errors.New("boss needs at least one phase"))
+		}
+		for i, phase := range d.Boss.Phases {
+			name := fmt.Sprintf("boss.phases[%d]", i)
+			if i == 0 && phase.HealthBelow != 1 {
+				errs = append(errs, fmt.Errorf("%s.healthBelow must be 1 for the first phase, got %v", name, phase.HealthBelow))
+			}
+			if i > 0 && (phase.HealthBelow <= 0 || phase.HealthBelow >= d.Boss.Phases[i-1].HealthBelow) {
+				errs = append(errs, fmt.Errorf("%s.healthBelow must be positive and below the previous phase, got %v", name, phase.HealthBelow))
+			}
+			positive(name+".speedMultiplier", phase.SpeedMultiplier)
+			if phase.Special.Tag == "" {
+				errs = append(errs, fmt.Errorf("%s.special.tag is required", name))
+			}
+			nonNegative(name+".special.telegraph", phase.Special.Telegraph)
+			positive(name+".special.every", phase.Special.Every)
+			attacks = append(attacks, namedAttack{name + ".special", phase.Special.EnemyAttack})
+		}
+	}
+
+	for _, a := range attacks {
+		name, attack := a.name, a.attack
+		positive(name+".reach", attack.Reach)
+		nonNegative(name+".damage", attack.Damage)
+		nonNegative(name+".knockback", attack.Knockback)
+		positive(name+".frameDuration", attack.FrameDuration)
+		nonNegative(name+".cooldown", attack.Cooldown)
+		if attack.ActiveFrames[0] < 0 || attack.ActiveFrames[1] < attack.ActiveFrames[0] {
+			errs = append(errs, fmt.Errorf("%s.activeFrames must be an ascending pair of non-negative frames, got %v", name, attack.ActiveFrames))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// SpriteSheet is a loaded Aseprite file with its frames converted to images
+// once, so enemies sharing a sprite also share its frame images
+type SpriteSheet struct {
+	File   *aseprite.File
+	frames []*ebiten.Image
+}
+
+// Frame returns the image for a frame index, converting it on first use
+func (s *SpriteSheet) Frame(index int) (*ebiten.Image, error) {
+	if index < 0 || index >= len(s.frames) {
+		return nil, fmt.Errorf("frame index %d out of range", index)
+	}
+	if s.frames[index] == nil {
+		img, err := s.File.GetFrameImage(index)
+		if err != nil {
+			return nil, err
+		}
+		s.frames[index] = ebiten.NewImageFromImage(img)
+	}
+	return s.frames[index], nil
+}
+
+// TagRange returns the frame range of the named tag
+func (s *SpriteSheet) TagRange(name string) (from, to int, ok bool) {
+	for _, tag := range s.File.Tags {
+		if tag.Name == name {
+			return int(tag.FromFrame), int(tag.ToFrame), true
+		}
+	}
+	return 0, 0, false
+}
+
+// EnemyFactory creates enemies from their definitions, loading each sprite only once
+type EnemyFactory struct {
+	definitions map[string]*EnemyDefinition
+	sprites     map[string]*SpriteSheet
+	tuning      *Tuning
+}
+
+// NewEnemyFactory creates a factory for the given definitions
+func NewEnemyFactory(definitions map[string]*EnemyDefinition, tuning *Tuning) *EnemyFactory {
+	return &EnemyFactory{
+		definitions: definitions,
+		sprites:     map[string]*SpriteSheet{},
+		tuning:      tuning,
+	}
+}
+
+// Has reports whether an enemy type is defined
+func (f *EnemyFactory) Has(enemyType string) bool {
+	_, ok := f.definitions[enemyType]
+	return ok
+}
+
+// Spawn creates an enemy of the given type at a position
+func (f *EnemyFactory) Spawn(enemyType string, x, y float64) (*Orc, error) {
+	def, ok := f.definitions[enemyType]
+	if !ok {
+		return nil, fmt.Errorf("unknown enemy type %q", enemyType)
+	}
+
+	sheet, err := f.spriteSheet(def.Sprite)
+	if err != nil {
+		return nil, err
+	}
+
+	orc, err := NewOrc(def, sheet, x, y, f.tuning)
+	if err != nil {
+		return nil, err
+	}
+	orc.enemyType = enemyType
+	return orc, nil
+}
+
+// spriteSheet returns the cached sprite sheet for a path, loading it if needed
+func (f *EnemyFactory) spriteSheet(path string) (*SpriteSheet, error) {
+	if sheet, ok := f.sprites[path]; ok {
+		return sheet, nil
+	}
+
+	file, err := aseprite.LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sheet := &SpriteSheet{File: file, frames: make([]*ebiten.Image, len(file.Frames))}
+	f.sprites[path] = sheet
+	return sheet, nil
+}
+
+// Reload replaces definitions in place so living enemies pick up new values.
+// Types removed from the file keep their old definition until the next restart,
+// and sprite or tag changes only apply to enemies spawned after the reload.
+func (f *EnemyFactory) Reload(definitions map[string]*EnemyDefinition) {
+	for name, def := range definitions {
+		if existing, ok := f.definitions[name]; ok {
+			// Living bosses index their phases, so the phase count can't change under them
+			if bossPhaseCount(existing) != bossPhaseCount(def) {
+				log.Printf("Not reloading %q: boss phases can only be added or removed on restart", name)
+				continue
+			}
+			*existing = *def
+		} else {
+			f.definitions[name] = def
+		}
+	}
+}
+
+// bossPhaseCount returns how many phases an enemy's boss fight has, 0 for regular enemies
+func bossPhaseCount(def *EnemyDefinition) int {
+	if def.Boss == nil {
+		return 0
+	}
+	return len(def.Boss.Phases)
+}
@@ -0,0 +1,74 @@
+package main
+
+// CombatEventKind identifies what happened in a CombatEvent
+type CombatEventKind int
+
+const (
+	EventBlock        CombatEventKind = iota // The player blocked a hit
+	EventParry                               // The player parried a hit
+	EventSwing                               // The player started a swing
+	EventEnemyHit                            // An enemy took damage and survived
+	EventEnemyKilled                         // An enemy took a killing blow
+	EventEnemyRemoved                        // A dead enemy's body disappeared
+	EventPlayerHit                           // The player took damage
+	EventPlayerHealed                        // The player regained health
+	EventPickup                              // The player collected a pickup
+)
+
+// combatEventNames names event kinds in data files
+var combatEventNames = map[string]CombatEventKind{
+	"block":        EventBlock,
+	"parry":        EventParry,
+	"swing":        EventSwing,
+	"enemyHit":     EventEnemyHit,
+	"enemyKilled":  EventEnemyKilled,
+	"enemyRemoved": EventEnemyRemoved,
+	"playerHit":    EventPlayerHit,
+	"playerHealed": EventPlayerHealed,
+	"pickup":       EventPickup,
+}
+
+// CombatEvent is something that happened in a fight which audio and visual
+// effects can react to. Positions are in world coordinates.
+type CombatEvent struct {
+	Kind      CombatEventKind
+	X, Y      float64
+	Direction float64 // 1 if the swing or blow was heading right, -1 if left
+	Amount    float64 // Damage dealt or health regained, if any
+	Critical  bool    // The hit was a critical hit
+	Name      string  // Name of the enemy or pickup involved, if any
+}
+
+// blowDirection returns which way a blow from sourceX travels to reach targetX
+func blowDirection(sourceX, targetX float64) float64 {
+	if targetX < sourceX {
+		return -1
+	}
+	return 1
+}
+
+// facingDirection returns 1 for a fighter facing right and -1 for one facing left
+func facingDirection(facingLeft bool) float64 {
+	if facingLeft {
+		return -1
+	}
+	return 1
+}
+
+// CombatEvents hands combat events from the simulation to the systems that
+// react to them. Listeners run immediately, in the order they were added.
+type CombatEvents struct {
+	listeners []func(CombatEvent)
+}
+
+// Listen registers a function to be called for every event
+func (e *CombatEvents) Listen(listener func(CombatEvent)) {
+	e.listeners = append(e.listeners, listener)
+}
+
+// Emit sends an event to every listener
+func (e *CombatEvents) Emit(event CombatEvent) {
+	for _, listener := range e.listeners {
+		listener(event)
+	}
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+
+	"rpg_demo/aseprite"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"golang.org/x/image/font/basicfont"
+)
+
+const fontsPath = "assets/fonts.json"
+
+// FontSource is where one font comes from: a TrueType or OpenType file, a
+// bitmap font drawn in Aseprite, or the small built-in font if File is empty
+type FontSource struct {
+	File    string  `json:"file"`    // A .ttf, .otf or .aseprite file
+	Size    float64 `json:"size"`    // Size in pixels for .ttf and .otf fonts
+	Glyphs  string  `json:"glyphs"`  // Bitmap fonts: the characters in the sheet, left to right then top to bottom
+	Cell    [2]int  `json:"cell"`    // Bitmap fonts: width and height of each character's cell
+	Scale   float64 `json:"scale"`   // Bitmap fonts: size multiplier, whole numbers keep the pixels crisp
+	Spacing float64 `json:"spacing"` // Bitmap fonts: extra pixels between characters, before scaling
+}
+
+// Validate checks the source's settings suit its kind of font. The file itself is checked when it's loaded.
+func (s *FontSource) Validate() error {
+	var errs []error
+	switch filepath.Ext(s.File) {
+	case "":
+		if s.File != "" {
+			errs = append(errs, fmt.Errorf("file must be a .ttf, .otf or .aseprite file, got %q", s.File))
+		}
+	case ".ttf", ".otf":
+		if s.Size <= 0 {
+			errs = append(errs, fmt.Errorf("size must be positive, got %v", s.Size))
+		}
+	case ".aseprite":
+		if s.Glyphs == "" {
+			errs = append(errs, errors.New("glyphs must list the characters in the sheet"))
+		}
+		if s.Cell[0] <= 0 || s.Cell[1] <= 0 {
+			errs = append(errs, fmt.Errorf("cell must be a positive width and height, got %v", s.Cell))
+		}
+		if s.Scale <= 0 {
+			errs = append(errs, fmt.Errorf("scale must be positive, got %v", s.Scale))
+		}
+		if s.Spacing < 0 {
+			errs = append(errs, fmt.Errorf("spacing cannot be negative, got %v", s.Spacing))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("file must be a .ttf, .otf or .aseprite file, got %q", s.File))
+	}
+	return errors.Join(errs...)
+}
+
+// FontConfig is everything in fontsPath: the font for each kind of text
+type FontConfig struct {
+	HUD     FontSource `json:"hud"`     // Counters, labels, the kill feed and the menu
+	Large   FontSource `json:"large"`   // Announcements and kill streaks
+	Numbers FontSource `json:"numbers"` // Floating damage numbers
+}
+
+// LoadFontConfig reads and validates the font data file
+func LoadFontConfig(path string) (*FontConfig, error) {
+	config := &FontConfig{}
+	if err := decodeDataFile(path, "font", config, config.Validate); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate checks every font source
+func (c *FontConfig) Validate() error {
+	var errs []error
+	check := func(name string, source *FontSource) {
+		if err := source.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	check("hud", &c.HUD)
+	check("large", &c.Large)
+	check("numbers", &c.Numbers)
+	return errors.Join(errs...)
+}
+
+// Font draws single lines of text. Text is positioned by its top left corner,
+// whatever kind of font it is, so layout code never deals with baselines.
+type Font interface {
+	// Draw draws s with its top left corner at the origin of opts.GeoM
+	Draw(screen *ebiten.Image, s string, opts *ebiten.DrawImageOptions)
+	// Measure returns the size of s in pixels
+	Measure(s string) (width, height float64)
+}
+
+// Fonts is every loaded font, as set in FontConfig
+type Fonts struct {
+	HUD     Font
+	Large   Font
+	Numbers Font
+}
+
+// LoadFonts loads the fonts in a config. Sources sharing a .ttf or .otf file
+// only parse it once.
+func LoadFonts(config *FontConfig) (*Fonts, error) {
+	parsed := map[string]*text.GoTextFaceSource{}
+	load := func(name string, source FontSource) (Font, error) {
+		f, err := loadFont(source, parsed)
+		if err != nil {
+			return nil, fmt.Errorf("%s font: %w", name, err)
+		}
+		return f, nil
+	}
+
+	fonts := &Fonts{}
+	var err error
+	if fonts.HUD, err = load("hud", config.HUD); err != nil {
+		return nil, err
+	}
+	if fonts.Large, err = load("large", config.Large); err != nil {
+		return nil, err
+	}
+	if fonts.Numbers, err = load("numbers", config.Numbers); err != nil {
+		return nil, err
+	}
+	return fonts, nil
+}
+
+// loadFont loads one font source, reusing already parsed font files
+func loadFont(source FontSource, parsed map[string]*text.GoTextFaceSource) (Font, error) {
+	switch filepath.Ext(source.File) {
+	case ".ttf", ".otf":
+		faceSource, ok := parsed[source.File]
+		if !ok {
+			data, err := os.ReadFile(source.File)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", source.File, err)
+			}
+			if faceSource, err = text.NewGoTextFaceSource(bytes.NewReader(data)); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", source.File, err)
+			}
+			parsed[source.File] = faceSource
+		}
+		return newFaceFont(&text.GoTextFace{Source: faceSource, Size: source.Size}), nil
+	case ".aseprite":
+		return LoadBitmapFont(source)
+	default:
+		return newFaceFont(text.NewGoXFace(basicfont.Face7x13)), nil
+	}
+}
+
+// faceFont is a Font drawn from a text face, such as a TrueType font or the built-in font
+type faceFont struct {
+	face   text.Face
+	height float64
+	opts   text.DrawOptions // Reused so drawing doesn't allocate
+}
+
+// newFaceFont wraps a face as a Font
+func newFaceFont(face text.Face) *faceFont {
+	metrics := face.Metrics()
+	return &faceFont{
+		face:   face,
+		height: math.Ceil(metrics.HAscent + metrics.HDescent),
+	}
+}
+
+// Draw draws s with its top left corner at the origin of opts.GeoM
+func (f *faceFont) Draw(screen *ebiten.Image, s string, opts *ebiten.DrawImageOptions) {
+	// The default layout puts the top of the line at the origin, so no baseline offset is needed
+	f.opts.DrawImageOptions = *opts
+	text.Draw(screen, s, f.face, &f.opts)
+}
+
+// Measure returns the size of s in pixels
+func (f *faceFont) Measure(s string) (width, height float64) {
+	return math.Ceil(text.Advance(s, f.face)), f.height
+}
+
+// BitmapFont is a pixel font drawn in Aseprite: every character sits in its own
+// cell of a grid on the first frame. Characters are as wide as their rightmost
+// opaque pixel, so a monospaced grid still gives proportional text.
+type BitmapFont struct {
+	glyphs   map[rune]*ebiten.Image // Each character's cell, cut from the sheet once
+	advances map[rune]float64       // How far each character moves the pen, before scaling
+	height   float64
+	scale    float64
+	spacing  float64
+}
+
+// LoadBitmapFont loads a bitmap font from an .aseprite file
+func LoadBitmapFont(source FontSource) (*BitmapFont, error) {
+	file, err := aseprite.LoadFile(source.File)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", source.File, err)
+	}
+	img, err := file.GetFrameImage(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", source.File, err)
+	}
+
+	cellWidth, cellHeight := source.Cell[0], source.Cell[1]
+	columns := img.Bounds().Dx() / cellWidth
+	rows := img.Bounds().Dy() / cellHeight
+	if count := utf8.RuneCountInString(source.Glyphs); count > columns*rows {
+		return nil, fmt.Errorf("%s has room for %d %dx%d characters, but %d glyphs are listed", source.File, columns*rows, cellWidth, cellHeight, count)
+	}
+
+	sheet := ebiten.NewImageFromImage(img)
+	f := &BitmapFont{
+		glyphs:   map[rune]*ebiten.Image{},
+		advances: map[rune]float64{},
+		height:   float64(cellHeight),
+		scale:    source.Scale,
+		spacing:  source.Spacing,
+	}
+	i := 0
+	for _, r := range source.Glyphs {
+		cell := image.Rect(0, 0, cellWidth, cellHeight).Add(image.Pt(i%columns*cellWidth, i/columns*cellHeight))
+		f.glyphs[r] = sheet.SubImage(cell).(*ebiten.Image)
+
+		// Empty cells, like the space, get half a cell
+		advance := float64(cellWidth) / 2
+		if right := rightmostOpaqueColumn(img, cell); right >= 0 {
+			advance = float64(right + 1)
+		}
+		f.advances[r] = advance
+		i++
+	}
+	return f, nil
+}
+
+// rightmostOpaqueColumn returns the last column of a cell with a visible pixel,
+// relative to the cell, or -1 if the cell is empty
+func rightmostOpaqueColumn(img image.Image, cell image.Rectangle) int {
+	for x := cell.Max.X - 1; x >= cell.Min.X; x-- {
+		for y := cell.Min.Y; y < cell.Max.Y; y++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a > 0 {
+				return x - cell.Min.X
+			}
+		}
+	}
+	return -1
+}
+
+// Draw draws s with its top left corner at the origin of opts.GeoM. Characters
+// missing from the font are skipped.
+func (f *BitmapFont) Draw(screen *ebiten.Image, s string, opts *ebiten.DrawImageOptions) {
+	glyphOpts := *opts
+	x := 0.0
+	for _, r := range s {
+		glyph, ok := f.glyphs[r]
+		if !ok {
+			continue
+		}
+		glyphOpts.GeoM.Reset()
+		glyphOpts.GeoM.Translate(x, 0)
+		glyphOpts.GeoM.Scale(f.scale, f.scale)
+		glyphOpts.GeoM.Concat(opts.GeoM)
+		screen.DrawImage(glyph, &glyphOpts)
+		x += f.advances[r] + f.spacing
+	}
+}
+
+// Measure returns the size of s in pixels
+func (f *BitmapFont) Measure(s string) (width, height float64) {
+	for _, r := range s {
+		if advance, ok := f.advances[r]; ok {
+			width += advance + f.spacing
+		}
+	}
+	if width > 0 {
+		width -= f.spacing // No gap after the last character
+	}
+	return width * f.scale, f.height * f.scale
+}
+
+// drawCenteredText draws a line of text scaled and centered on a screen position
+func drawCenteredText(screen *ebiten.Image, f Font, s string, x, y, scale float64, opts *ebiten.DrawImageOptions) {
+	width, height := f.Measure(s)
+	opts.GeoM.Reset()
+	opts.GeoM.Translate(-width/2, -height/2)
+	opts.GeoM.Scale(scale, scale)
+	opts.GeoM.Translate(x, y)
+	f.Draw(screen, s, opts)
+}
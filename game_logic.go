@@ -2,81 +2,97 @@ package main
 
 import (
 	"log"
-	"os"
+	"math"
+	"slices"
 
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
-// spawnOrc creates a new orc at a random off-screen position with increasing speed
-func (g *Game) spawnOrc() {
-	// Randomly choose left or right side of screen (50/50 chance)
-	var spawnX float64
-	if len(g.orcs)%2 == 0 {
-		// Spawn on the left side (off-screen)
-		spawnX = -float64(screenWidth)/2 - 200
-	} else {
-		// Spawn on the right side (off-screen)
-		spawnX = float64(screenWidth)/2 + 200
-	}
-
-	// Create new orc with increased speed based on kills
-	orc, err := NewOrc(spawnX, float64(screenHeight)*0.2)
+// spawnEnemy creates an enemy requested by the spawn director, scaled for the current wave.
+// Spawn points are relative to the camera, so enemies enter from the edges of the view
+// wherever the player is in the level, at a random depth of the arena.
+func (g *Game) spawnEnemy(req SpawnRequest) {
+	arena := g.tuning.Arena
+	y := arena.DepthMin + g.rng.Float64()*(arena.DepthMax-arena.DepthMin)
+	orc, err := g.enemies.Spawn(req.Type, g.camera.X+req.X, y)
 	if err != nil {
-		log.Printf("Failed to create new orc: %v", err)
+		log.Printf("Failed to create new %s: %v", req.Type, err)
 		return
 	}
 
-	// Increase orc speed based on kills (each kill makes orcs 5% faster)
-	speedMultiplier := 1.0 + (float64(g.orcsKilled) * 0.05)
-	orc.walkSpeed = 2.0 * speedMultiplier
+	// Scale speed and health for the wave (health is rounded, but never below 1)
+	orc.walkSpeed *= req.SpeedMultiplier
+	orc.maxHealth = max(1, int(math.Round(float64(orc.maxHealth)*req.HealthMultiplier)))
+	orc.health = orc.maxHealth
+	orc.SetPatrolArea(g.camera.X)
 
 	// Add to orcs slice
 	g.orcs = append(g.orcs, orc)
 
-	// Decrease spawn interval slightly (make spawns faster)
-	g.spawnInterval = g.spawnInterval * 0.95
-	if g.spawnInterval < 0.5 {
-		g.spawnInterval = 0.5 // Minimum spawn interval of 0.5 seconds
+	if orc.def.Boss != nil {
+		g.startBoss(orc)
 	}
 }
 
+// aliveOrcCount returns the number of orcs that haven't been killed yet
+func (g *Game) aliveOrcCount() int {
+	count := 0
+	for _, orc := range g.orcs {
+		if orc != nil && orc.IsAlive() {
+			count++
+		}
+	}
+	return count
+}
+
 // handlePlayerInput processes player input for movement and attacks
 func (g *Game) handlePlayerInput() {
-	// Handle attack input (only if not already attacking and not hurt)
-	if ebiten.IsKeyPressed(ebiten.KeySpace) && !g.isAttacking && g.playerState == PlayerStateAlive {
-		g.isAttacking = true
-		g.currentFrame = g.attackFrameStart
-		g.frameTimer = 0
+	g.updateComboTimers()
+	g.updateDodge()
 
-		// Play attack sound effect
-		if g.attackPlayer != nil {
-			g.attackPlayer.Rewind()
-			g.attackPlayer.Play()
-		}
+	// Handle dodge input first so a roll can cancel a swing. Presses made while
+	// the roll isn't ready yet are buffered briefly.
+	if g.canDodge() && g.input.ConsumePress(ActionDodge) {
+		g.startDodge()
+	}
+	g.updateBlock()
+
+	// Handle attack input (only if not hurt, rolling or blocking). Presses during a swing chain the combo,
+	// and presses made while hurt are buffered briefly so they aren't lost.
+	if g.playerState == PlayerStateAlive && !g.isDodging && !g.isBlocking && g.input.Triggered(ActionAttack, g.settings.Input.AttackMode) {
+		g.handleAttackPress()
 	}
 
-	// Handle movement input (only if not attacking and not hurt)
-	if !g.isAttacking && g.playerState == PlayerStateAlive {
+	// Handle movement input (only if not attacking, rolling, blocking or hurt)
+	if !g.isAttacking && !g.isDodging && !g.isBlocking && g.playerState == PlayerStateAlive {
 		wasWalking := g.isWalking
 		g.isWalking = false
 
-		if ebiten.IsKeyPressed(ebiten.KeyArrowLeft) || ebiten.IsKeyPressed(ebiten.KeyA) {
+		// Upgrades and speed buffs scale walking in every direction
+		speed := g.stats.MoveSpeed * g.buffs.Multiplier(BuffSpeed)
+
+		if g.input.IsHeld(ActionMoveLeft) {
 			g.isWalking = true
 			g.facingLeft = true
-			g.positionX -= g.walkSpeed
-			// Keep within screen bounds
-			if g.positionX < -float64(screenWidth)/2 {
-				g.positionX = -float64(screenWidth) / 2
-			}
+			g.positionX -= g.tuning.Player.WalkSpeed * speed
+			// Keep within the level
+			g.positionX = g.level.ClampX(g.positionX)
 		}
-		if ebiten.IsKeyPressed(ebiten.KeyArrowRight) || ebiten.IsKeyPressed(ebiten.KeyD) {
+		if g.input.IsHeld(ActionMoveRight) {
 			g.isWalking = true
 			g.facingLeft = false
-			g.positionX += g.walkSpeed
-			// Keep within screen bounds
-			if g.positionX > float64(screenWidth)/2 {
-				g.positionX = float64(screenWidth) / 2
-			}
+			g.positionX += g.tuning.Player.WalkSpeed * speed
+			// Keep within the level
+			g.positionX = g.level.ClampX(g.positionX)
+		}
+		// Walk up and down the arena, staying on its floor
+		if g.input.IsHeld(ActionMoveUp) {
+			g.isWalking = true
+			g.positionY = g.tuning.Arena.ClampDepth(g.positionY - g.tuning.Player.DepthSpeed*speed)
+		}
+		if g.input.IsHeld(ActionMoveDown) {
+			g.isWalking = true
+			g.positionY = g.tuning.Arena.ClampDepth(g.positionY + g.tuning.Player.DepthSpeed*speed)
 		}
 
 		// Switch animation if walking state changed
@@ -105,16 +121,16 @@ func (g *Game) updatePlayerDeath() {
 			if g.flashTimer <= 0 {
 				// Toggle visibility
 				g.flashVisible = !g.flashVisible
-				g.flashTimer = 0.1 // Flash every 0.1 seconds
+				g.flashTimer = g.tuning.Player.FlashInterval
 
 				if !g.flashVisible {
 					g.flashCount++
 				}
 
-				// After 6 flashes (3 on/off cycles), exit the game
-				if g.flashCount >= 6 {
-					log.Printf("Game Over! Player died after killing %d orcs.", g.orcsKilled)
-					os.Exit(0)
+				// After enough flashes, end the run
+				if g.flashCount >= g.tuning.Player.FlashCount && !g.gameOver {
+					g.gameOver = true
+					g.recordRun()
 				}
 			}
 		}
@@ -125,9 +141,20 @@ func (g *Game) updatePlayerDeath() {
 func (g *Game) updatePlayerAnimation() {
 	// Update animation timer
 	g.frameTimer += 1.0 / 60.0 // Assuming 60 FPS
+	if g.hitFlash > 0 {
+		g.hitFlash -= 1.0 / 60.0
+	}
+
+	// Attacks and rolls have their own animation speed, and attack speed upgrades shorten swings
+	frameDuration := g.tuning.Player.FrameDuration
+	if g.isAttacking {
+		frameDuration = g.currentPlayerAttack().FrameDuration / g.stats.AttackSpeed
+	} else if g.isDodging {
+		frameDuration = g.dodgeFrameDuration()
+	}
 
 	// Check if it's time to advance to the next frame
-	if g.frameTimer >= g.frameDuration {
+	if g.frameTimer >= frameDuration {
 		g.frameTimer = 0
 		g.currentFrame++
 
@@ -144,14 +171,17 @@ func (g *Game) updatePlayerAnimation() {
 				g.currentFrame = g.idleFrameStart
 			}
 		} else if g.isAttacking {
-			if g.currentFrame > g.attackFrameEnd {
-				// Attack animation finished, return to appropriate state
-				g.isAttacking = false
-				if g.isWalking {
-					g.currentFrame = g.walkFrameStart
-				} else {
-					g.currentFrame = g.idleFrameStart
-				}
+			if _, attackEnd := g.attackFrames(); g.currentFrame > attackEnd {
+				// Attack animation finished, chain the combo or return to appropriate state
+				g.finishSwing()
+			}
+		} else if g.isBlocking {
+			// Hold the guard pose
+			g.currentFrame = g.blockFrame()
+		} else if g.isDodging {
+			if _, dodgeEnd := g.dodgeFrames(); g.currentFrame > dodgeEnd {
+				// Hold the last frame; updateDodge ends the roll on its timer
+				g.currentFrame = dodgeEnd
 			}
 		} else if g.isWalking {
 			if g.currentFrame > g.walkFrameEnd {
@@ -164,24 +194,45 @@ func (g *Game) updatePlayerAnimation() {
 		}
 
 		// Update the sprite image to the current frame
-		if g.asepriteFile != nil {
-			frameImg, err := g.asepriteFile.GetFrameImage(g.currentFrame)
-			if err == nil {
-				g.soldierSprite = ebiten.NewImageFromImage(frameImg)
-			}
+		g.refreshPlayerSprite()
+	}
+}
+
+// refreshPlayerSprite renders the current frame, with only the layers the wielded weapon uses
+func (g *Game) refreshPlayerSprite() {
+	if g.asepriteFile == nil {
+		return
+	}
+	layers := g.weapon().Layers
+	fileLayers := g.asepriteFile.Layers
+	frameImg, err := g.asepriteFile.GetFrameImageLayers(g.currentFrame, func(layer int) bool {
+		if len(layers) == 0 {
+			return true
 		}
+		// A cel on a layer the file never declared can't be one the weapon asked for
+		return layer < len(fileLayers) && slices.Contains(layers, fileLayers[layer].Name)
+	})
+	if err == nil {
+		g.soldierSprite = ebiten.NewImageFromImage(frameImg)
 	}
 }
 
 // updateOrcLogic handles orc updates, interactions, and spawning
 func (g *Game) updateOrcLogic() {
-	// Update spawn timer
-	g.spawnTimer += 1.0 / 60.0 // Assuming 60 FPS
+	// Let the spawn director decide whether new enemies arrive this tick
+	for _, req := range g.director.Update(g.aliveOrcCount(), g.orcsKilled, g.boss != nil) {
+		g.spawnEnemy(req)
+	}
 
-	// Check if it's time to spawn a new orc
-	if g.spawnTimer >= g.spawnInterval {
-		g.spawnOrc()
-		g.spawnTimer = 0 // Reset spawn timer
+	// Snapshot where every living orc stands so brains can keep their spacing
+	g.crowd = g.crowd[:0]
+	crowdIndex := make([]int, len(g.orcs))
+	for i, orc := range g.orcs {
+		crowdIndex[i] = -1
+		if orc != nil && orc.IsAlive() {
+			crowdIndex[i] = len(g.crowd)
+			g.crowd = append(g.crowd, orc.positionX)
+		}
 	}
 
 	// Update all orcs and handle interactions
@@ -191,86 +242,219 @@ func (g *Game) updateOrcLogic() {
 			continue
 		}
 
-		// Store previous health to detect damage
-		prevHealth := orc.GetHealth()
-		wasAlive := orc.IsAlive()
-
-		orc.Update(g.positionX)
+		orc.Update(&AIContext{
+			PlayerX:          g.positionX,
+			PlayerY:          g.positionY,
+			PlayerFacingLeft: g.facingLeft,
+			HitDepth:         g.tuning.Arena.HitDepth,
+			Crowd:            g.crowd,
+			SelfIndex:        crowdIndex[i],
+		})
 
 		// Check if orc should be removed after death sequence
 		if orc.ShouldRemove() {
-			g.orcsKilled++ // Increment kill counter
+			g.events.Emit(CombatEvent{Kind: EventEnemyRemoved, X: orc.positionX, Y: orc.positionY, Direction: facingDirection(orc.facingLeft)})
 			// Remove the orc from the slice
 			g.orcs = append(g.orcs[:i], g.orcs[i+1:]...)
 			continue
 		}
 
 		// Check if player attack hits this orc (using directional attack range)
-		if g.isAttacking && orc.IsAlive() && orc.CheckCollisionWithPlayerAttack(g.positionX, 0, g.facingLeft) {
+		// Each swing hits an orc at most once, and only on its active frames
+		if g.meleeAttackActive() && orc.IsAlive() && orc.lastPlayerSwing != g.swingID &&
+			orc.CheckCollisionWithPlayerAttack(g.positionX, g.positionY, g.attackReach(), g.facingLeft) {
 			// Player attack hits the orc
-			orc.TakeDamage(g.positionX)
-
-			// Check if orc took damage and play appropriate sound
-			currentHealth := orc.GetHealth()
-			if currentHealth < prevHealth {
-				if currentHealth <= 0 && wasAlive {
-					// Orc died - play death sound
-					if g.orcDiePlayer != nil {
-						g.orcDiePlayer.Rewind()
-						g.orcDiePlayer.Play()
-					}
-				} else {
-					// Orc took damage but didn't die - play hit sound
-					if g.orcHitPlayer != nil {
-						g.orcHitPlayer.Rewind()
-						g.orcHitPlayer.Play()
-					}
-				}
+			orc.lastPlayerSwing = g.swingID
+			if g.hitOrc(orc, g.playerHit()) {
+				g.registerComboHit()
 			}
 		}
 
-		// Check for collision between player and this orc (only if orc is alive and player is not already hurt or dying)
-		if orc.IsAlive() && g.playerState == PlayerStateAlive && orc.CheckCollisionWithPlayer(g.positionX, 0) {
-			// Player takes damage
-			g.playerHealth -= 10.0
-			if g.playerHealth <= 0 {
-				g.playerHealth = 0
-				// Player dies - start death sequence
-				g.playerState = PlayerStateDying
-				g.currentFrame = g.deathFrameStart
-				g.frameTimer = 0
-				g.deathTimer = 3.0    // Wait 3 seconds before flashing
-				g.isAttacking = false // Cancel any ongoing attack
-				g.isWalking = false   // Cancel any ongoing movement
-			} else {
-				// Set player to hurt state and start hurt animation
-				g.playerState = PlayerStateHurt
-				g.currentFrame = g.hurtFrameStart
-				g.frameTimer = 0
-				g.isAttacking = false // Cancel any ongoing attack
-				g.isWalking = false   // Cancel any ongoing movement
+		// Check whether this orc's swing lands (not while the player is invulnerable or dying)
+		if orc.IsAlive() && g.canBeHit() {
+			if damage, knockback, hit := orc.CheckAttackHitPlayer(g.positionX, g.positionY); hit {
+				special := orc.state == OrcStateSpecial
+				if g.receiveHit(damage, knockback, orc.positionX) {
+					orc.Stun(g.tuning.Player.Block.StunDuration)
+				} else if special {
+					// A boss's special attack landing shakes the whole screen
+					g.juice.Shake(g.tuning.Juice.SpecialTrauma)
+				}
 			}
+		}
 
-			// Simple knockback effect - push player away from orc (5x stronger knockback)
-			// Compare player position directly with orc position (both use same coordinate system)
-			if g.positionX < orc.positionX {
-				// Player is to the left of orc, push player further left (away from orc)
-				g.positionX -= 100
-			} else {
-				// Player is to the right of orc, push player further right (away from orc)
-				g.positionX += 100
+		// Ranged enemies throw instead of striking
+		if projectile, thrown := orc.ReleaseProjectile(g.positionX); thrown {
+			g.projectiles = append(g.projectiles, projectile)
+		}
+	}
+
+	g.shootWeapon()
+	g.updateProjectiles()
+
+	g.separateOrcs()
+	g.updateBoss()
+}
+
+// hitOrc damages an orc, heals the player by their lifesteal and plays the matching sound.
+// It reports whether the hit did damage (it may have been blocked).
+func (g *Game) hitOrc(orc *Orc, hit Hit) bool {
+	prevHealth := orc.GetHealth()
+	wasAlive := orc.IsAlive()
+	orc.TakeDamage(hit)
+
+	// Check if orc took damage and play appropriate sound
+	currentHealth := orc.GetHealth()
+	if currentHealth < prevHealth {
+		event := CombatEvent{
+			Kind:      EventEnemyHit,
+			X:         orc.positionX,
+			Y:         orc.positionY,
+			Direction: blowDirection(hit.SourceX, orc.positionX),
+			Amount:    float64(prevHealth - max(currentHealth, 0)),
+			Critical:  hit.Critical,
+			Name:      orc.def.Name,
+		}
+		if currentHealth <= 0 && wasAlive {
+			// The kill counts the moment the blow lands, not when the body disappears
+			event.Kind = EventEnemyKilled
+			g.orcsKilled++
+			g.score += orc.def.ScoreValue
+			g.gainXP(orc.def.XP)
+			g.dropLoot(orc)
+		}
+		g.events.Emit(event)
+		g.lifesteal(event.Amount) // Only the player hits enemies, so every blow counts
+
+		if currentHealth <= 0 && wasAlive {
+			// Orc died - play death sound
+			if g.orcDiePlayer != nil {
+				g.orcDiePlayer.Rewind()
+				g.orcDiePlayer.Play()
 			}
+		} else {
+			// Orc took damage but didn't die - play hit sound
+			if g.orcHitPlayer != nil {
+				g.orcHitPlayer.Rewind()
+				g.orcHitPlayer.Play()
+			}
+		}
+	}
+	return currentHealth < prevHealth
+}
+
+// updateProjectiles moves projectiles and resolves what they hit. The player's
+// attack deflects enemy projectiles, which then hurt enemies instead.
+func (g *Game) updateProjectiles() {
+	bodyX, bodyY, bodyW, bodyH := playerBodyBounds(g.tuning, g.positionX, g.positionY)
+	attackX, attackY, attackW, attackH := playerAttackBounds(g.attackReach(), g.positionX, g.positionY, g.facingLeft)
+
+	for _, p := range g.projectiles {
+		p.Update()
+		if math.Abs(p.X) > g.level.Width/2+screenWidth {
+			p.dead = true // Flew well past the end of the level
+		}
+		x, y, w, h := p.Bounds()
 
-			// Keep player within screen bounds after knockback
-			if g.positionX < -float64(screenWidth)/2 {
-				g.positionX = -float64(screenWidth) / 2
+		// Projectiles fly along one line of the arena and pass fighters on other lines
+		if p.CanHitPlayer() && withinHitDepth(g.tuning, p.Depth, g.positionY) {
+			switch {
+			case g.meleeAttackActive() && boxesOverlap(attackX, attackY, attackW, attackH, x, y, w, h):
+				p.Deflect(g.facingLeft, g.tuning.Player.Deflect)
+				if g.attackPlayer != nil {
+					g.attackPlayer.Rewind()
+					g.attackPlayer.Play()
+				}
+			case g.canBeHit() && boxesOverlap(bodyX, bodyY, bodyW, bodyH, x, y, w, h):
+				if g.receiveHit(p.Damage, p.Knockback, p.SourceX()) {
+					p.Deflect(g.facingLeft, g.tuning.Player.Deflect) // Parried projectiles fly back
+				} else {
+					p.dead = true
+				}
 			}
-			if g.positionX > float64(screenWidth)/2 {
-				g.positionX = float64(screenWidth) / 2
+		}
+
+		if p.CanHitEnemies() {
+			for _, orc := range g.orcs {
+				if orc == nil || !orc.IsAlive() || !withinHitDepth(g.tuning, p.Depth, orc.positionY) {
+					continue
+				}
+				if orcX, orcY, orcW, orcH := orc.GetBounds(); boxesOverlap(orcX, orcY, orcW, orcH, x, y, w, h) {
+					g.hitOrc(orc, Hit{SourceX: p.SourceX(), Damage: int(p.Damage), Knockback: p.Knockback})
+					p.dead = true
+					break
+				}
 			}
+		}
+	}
 
-			// Only take damage from one orc per frame
-			break
+	// Drop spent projectiles, reusing the slice
+	alive := g.projectiles[:0]
+	for _, p := range g.projectiles {
+		if !p.dead {
+			alive = append(alive, p)
 		}
 	}
+	clear(g.projectiles[len(alive):])
+	g.projectiles = alive
+}
+
+// separateOrcs keeps living orcs from overlapping, so crowds queue up behind
+// each other and knocked-back orcs shove the ones they slide into
+func (g *Game) separateOrcs() {
+	g.crowdBodies = g.crowdBodies[:0]
+	for i, orc := range g.orcs {
+		if orc != nil && orc.IsAlive() {
+			g.crowdBodies = append(g.crowdBodies, orc.crowdBody(i))
+		}
+	}
+
+	SeparateCrowd(g.crowdBodies, g.tuning.Arena.HitDepth)
+
+	for _, body := range g.crowdBodies {
+		orc := g.orcs[body.Index]
+		orc.positionX = body.X
+		orc.knockbackX = body.VelocityX
+	}
+}
+
+// damagePlayer applies a hit to the player, pushing them away from sourceX
+func (g *Game) damagePlayer(damage, knockback, sourceX float64) {
+	g.playerHealth -= damage
+	g.events.Emit(CombatEvent{Kind: EventPlayerHit, X: g.positionX, Y: g.positionY, Direction: blowDirection(sourceX, g.positionX), Amount: damage})
+	g.invulnTimer = g.tuning.Player.HitInvulnerability
+	g.isDodging = false // A hit outside the roll's invulnerability window ends it
+	g.isBlocking = false
+	if g.playerHealth <= 0 {
+		g.playerHealth = 0
+		// Player dies - start death sequence
+		g.playerState = PlayerStateDying
+		g.currentFrame = g.deathFrameStart
+		g.frameTimer = 0
+		g.deathTimer = g.tuning.Player.DeathDelay
+		g.isAttacking = false // Cancel any ongoing attack
+		g.isWalking = false   // Cancel any ongoing movement
+		g.breakCombo()
+	} else {
+		// Set player to hurt state and start hurt animation
+		g.playerState = PlayerStateHurt
+		g.currentFrame = g.hurtFrameStart
+		g.frameTimer = 0
+		g.isAttacking = false // Cancel any ongoing attack
+		g.isWalking = false   // Cancel any ongoing movement
+		g.breakCombo()
+	}
+
+	// Simple knockback effect - push player away from the attacker
+	// Compare player position directly with attacker position (both use same coordinate system)
+	if g.positionX < sourceX {
+		// Player is to the left of the attacker, push player further left
+		g.positionX -= knockback
+	} else {
+		// Player is to the right of the attacker, push player further right
+		g.positionX += knockback
+	}
+
+	// Keep player within the level after knockback
+	g.positionX = g.level.ClampX(g.positionX)
 }
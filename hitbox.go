@@ -0,0 +1,53 @@
+package main
+
+import "math"
+
+// playerSpriteScale is how much the soldier sprite is scaled up when drawn
+const playerSpriteScale = 10.0
+
+// playerBodyBounds returns the player's collision box in world coordinates
+func playerBodyBounds(tuning *Tuning, playerX, playerY float64) (x, y, width, height float64) {
+	// Player character collision box - smaller than the sprite for more precise collision (scaled up)
+	playerCharW := tuning.Player.BodySize * playerSpriteScale
+	playerCharH := tuning.Player.BodySize * playerSpriteScale
+
+	// Center the collision box on the player
+	return playerX - playerCharW/2, playerY - playerCharH/2, playerCharW, playerCharH
+}
+
+// playerAttackBounds returns the box an attack with the given reach (width and
+// height in sprite pixels) covers in world coordinates, in front of the player
+// in the direction they face
+func playerAttackBounds(reach [2]float64, playerX, playerY float64, facingLeft bool) (x, y, width, height float64) {
+	// Player attack range - larger than collision box (scaled up)
+	// This allows the player to hit the orc from a safer distance
+	attackRangeW := reach[0] * playerSpriteScale
+	attackRangeH := reach[1] * playerSpriteScale
+
+	// Position attack range based on facing direction
+	if facingLeft {
+		// Attack range is to the left of the player
+		x = playerX - attackRangeW
+	} else {
+		// Attack range is to the right of the player
+		x = playerX
+	}
+	y = playerY - attackRangeH/2
+
+	return x, y, attackRangeW, attackRangeH
+}
+
+// withinHitDepth reports whether two fighters are close enough in depth to hit
+// each other. Boxes overlapping on screen isn't enough: one fighter may be
+// standing further back in the arena than the other.
+func withinHitDepth(tuning *Tuning, aY, bY float64) bool {
+	return math.Abs(aY-bY) <= tuning.Arena.HitDepth
+}
+
+// boxesOverlap reports whether two axis-aligned boxes overlap
+func boxesOverlap(ax, ay, aw, ah, bx, by, bw, bh float64) bool {
+	return ax < bx+bw &&
+		ax+aw > bx &&
+		ay < by+bh &&
+		ay+ah > by
+}
@@ -0,0 +1,576 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Anchor is the point of the screen a HUD widget is placed relative to
+type Anchor int
+
+const (
+	AnchorTopLeft Anchor = iota
+	AnchorTop
+	AnchorTopRight
+	AnchorLeft
+	AnchorCenter
+	AnchorRight
+	AnchorBottomLeft
+	AnchorBottom
+	AnchorBottomRight
+)
+
+// Layout places a widget against an anchor. X and Y are the gap to the anchored
+// edges, so positive values always move into the screen: a widget anchored top
+// right with X 20 sits 20 pixels in from the right edge. On a centered axis,
+// positive values move right or down.
+type Layout struct {
+	Anchor Anchor
+	X, Y   float64
+}
+
+// Place returns the top left corner of a width by height box laid out on the screen
+func (l Layout) Place(width, height float64) (x, y float64) {
+	// Which third of the screen the anchor is in, on each axis
+	column, row := int(l.Anchor)%3, int(l.Anchor)/3
+
+	switch column {
+	case 0:
+		x = l.X
+	case 1:
+		x = (screenWidth-width)/2 + l.X
+	case 2:
+		x = screenWidth - width - l.X
+	}
+	switch row {
+	case 0:
+		y = l.Y
+	case 1:
+		y = (screenHeight-height)/2 + l.Y
+	case 2:
+		y = screenHeight - height - l.Y
+	}
+	return x, y
+}
+
+// Below returns the layout moved down by dy pixels on screen, whatever the anchor
+func (l Layout) Below(dy float64) Layout {
+	if l.Anchor >= AnchorBottomLeft {
+		l.Y -= dy
+	} else {
+		l.Y += dy
+	}
+	return l
+}
+
+// Bar is a HUD bar, such as a health bar. Its frame and fill are drawn into
+// images once when it's created, so drawing it never allocates.
+type Bar struct {
+	Layout Layout
+	Color  color.RGBA // Fill color, can be changed between frames
+
+	width, height float64
+	frame         *ebiten.Image // Border and empty background
+	fill          *ebiten.Image // White, tinted with Color and stretched to the filled fraction
+	opts          ebiten.DrawImageOptions
+}
+
+// NewBar creates a bar with a border around it
+func NewBar(layout Layout, width, height, border int, background, fill color.RGBA) *Bar {
+	frame := ebiten.NewImage(width+2*border, height+2*border)
+	frame.Fill(color.RGBA{20, 20, 20, 255})
+	inner := frame.SubImage(frame.Bounds().Inset(border)).(*ebiten.Image)
+	inner.Fill(background)
+
+	fillImage := ebiten.NewImage(width, height)
+	fillImage.Fill(color.White)
+
+	return &Bar{
+		Layout: layout,
+		Color:  fill,
+		width:  float64(width + 2*border),
+		height: float64(height + 2*border),
+		frame:  frame,
+		fill:   fillImage,
+	}
+}
+
+// Draw draws the bar filled to fraction, from 0 to 1
+func (b *Bar) Draw(screen *ebiten.Image, fraction float64) {
+	x, y := b.Layout.Place(b.width, b.height)
+
+	b.opts.GeoM.Reset()
+	b.opts.ColorScale.Reset()
+	b.opts.GeoM.Translate(x, y)
+	screen.DrawImage(b.frame, &b.opts)
+
+	fraction = math.Max(0, math.Min(1, fraction))
+	if fraction == 0 {
+		return
+	}
+	border := (b.width - float64(b.fill.Bounds().Dx())) / 2
+	b.opts.GeoM.Reset()
+	b.opts.GeoM.Scale(fraction, 1)
+	b.opts.GeoM.Translate(x+border, y+border)
+	b.opts.ColorScale.ScaleWithColor(b.Color)
+	screen.DrawImage(b.fill, &b.opts)
+}
+
+// Size returns the bar's size including its border
+func (b *Bar) Size() (width, height float64) {
+	return b.width, b.height
+}
+
+// Label is a line of HUD text. Its size is only measured again when the text changes.
+type Label struct {
+	Layout Layout
+	Font   Font
+	Color  color.RGBA
+
+	text          string
+	width, height float64
+	opts          ebiten.DrawImageOptions
+}
+
+// NewLabel creates a label with no text
+func NewLabel(layout Layout, f Font, c color.RGBA) *Label {
+	return &Label{Layout: layout, Font: f, Color: c}
+}
+
+// SetText changes what the label says
+func (l *Label) SetText(s string) {
+	if s == l.text {
+		return
+	}
+	l.text = s
+	l.width, l.height = l.Font.Measure(s)
+}
+
+// Draw draws the label, fading it by alpha from 0 to 1
+func (l *Label) Draw(screen *ebiten.Image, alpha float64) {
+	if l.text == "" || alpha <= 0 {
+		return
+	}
+	x, y := l.Layout.Place(l.width, l.height)
+	l.opts.GeoM.Reset()
+	l.opts.GeoM.Translate(x, y)
+	l.opts.ColorScale.Reset()
+	l.opts.ColorScale.ScaleWithColor(l.Color)
+	l.opts.ColorScale.ScaleAlpha(float32(alpha))
+	l.Font.Draw(screen, l.text, &l.opts)
+}
+
+// Icon is a small image on the HUD, such as a buff's symbol
+type Icon struct {
+	Layout Layout
+	Image  *ebiten.Image
+	Scale  float64
+
+	opts ebiten.DrawImageOptions
+}
+
+// Size returns the icon's size on screen
+func (i *Icon) Size() (width, height float64) {
+	bounds := i.Image.Bounds()
+	return float64(bounds.Dx()) * i.Scale, float64(bounds.Dy()) * i.Scale
+}
+
+// Draw draws the icon, fading it by alpha from 0 to 1
+func (i *Icon) Draw(screen *ebiten.Image, alpha float64) {
+	if i.Image == nil || alpha <= 0 {
+		return
+	}
+	x, y := i.Layout.Place(i.Size())
+	i.opts.GeoM.Reset()
+	i.opts.GeoM.Scale(i.Scale, i.Scale)
+	i.opts.GeoM.Translate(x, y)
+	i.opts.ColorScale.Reset()
+	i.opts.ColorScale.ScaleAlpha(float32(alpha))
+	screen.DrawImage(i.Image, &i.opts)
+}
+
+// Timer counts something down: a caption with the whole seconds left, over a
+// thin bar that drains as time runs out
+type Timer struct {
+	label *Label
+	bar   *Bar
+}
+
+// NewTimer creates a timer whose caption sits at layout, with the bar width pixels wide below it
+func NewTimer(layout Layout, f Font, c color.RGBA, width int) *Timer {
+	_, height := f.Measure("0")
+	return &Timer{
+		label: NewLabel(layout, f, c),
+		bar:   NewBar(layout.Below(height+4), width, 4, 1, color.RGBA{40, 40, 40, 255}, c),
+	}
+}
+
+// Draw draws the timer with remaining of total seconds left. The caption, if
+// any, is followed by the seconds, rounded up so the timer reads 1 until it runs out.
+func (t *Timer) Draw(screen *ebiten.Image, caption string, remaining, total float64) {
+	if remaining <= 0 {
+		return
+	}
+	seconds := fmt.Sprintf("%.0fs", math.Ceil(remaining))
+	if caption != "" {
+		seconds = caption + " " + seconds
+	}
+	t.label.SetText(seconds)
+	t.label.Draw(screen, 1)
+	t.bar.Draw(screen, remaining/total)
+}
+
+// HUD is the heads-up display drawn over the game. Every widget is created up
+// front, laid out against the edges of the screen.
+type HUD struct {
+	kills        *Label
+	score        *Label
+	wave         *Label
+	intermission *Timer
+	combo        *Label
+	crit         *Label
+	announcement *Label
+
+	health      *Bar
+	healthLabel *Label
+	stamina     *Bar
+	xp          *Bar
+	levelUpWait *Label // Level-ups saved up for the end of the wave
+
+	// The level-up screen, drawn while an upgrade is being chosen
+	levelUpShade *Bar
+	levelUpTitle *Label
+	levelUpHint  *Label
+	upgradeCards [maxUpgradeChoices]upgradeCard
+
+	bossBar  *Bar
+	bossName *Label
+
+	buffIcons  [hudBuffSlots]*Icon
+	buffTimers [hudBuffSlots]*Timer
+
+	weaponIcon *Icon
+	weaponName *Label
+
+	// The title and game-over screens, both showing the high score table
+	screenShade    *Bar
+	titleLabel     *Label
+	titlePrompt    *Label
+	gameOverLabel  *Label
+	gameOverRun    *Label
+	gameOverPlace  *Label
+	gameOverPrompt *Label
+	scores         *ScoreTable
+}
+
+// upgradeCard is one upgrade offered on the level-up screen. Cards are laid
+// out when drawn, since how many are offered changes as upgrades max out.
+type upgradeCard struct {
+	frame       *Bar
+	highlight   *Bar // Behind the frame of the selected card
+	name        *Label
+	description *Label
+	stacks      *Label
+}
+
+// Upgrade cards' size and the gap between them
+const (
+	upgradeCardWidth  = 300
+	upgradeCardHeight = 160
+	upgradeCardGap    = 30
+)
+
+// hudBuffSlots is how many active buffs the HUD has room for
+const hudBuffSlots = 6
+
+// hudIconScale is how much buff icons are scaled up in the HUD
+const hudIconScale = 3
+
+// NewHUD creates the HUD's widgets
+func NewHUD(fonts *Fonts) *HUD {
+	white := color.RGBA{255, 255, 255, 255}
+	gold := color.RGBA{255, 220, 80, 255}
+	_, line := fonts.HUD.Measure("0")
+	line += 6
+
+	// Counters down the top left corner
+	topLeft := Layout{Anchor: AnchorTopLeft, X: 20, Y: 20}
+	h := &HUD{
+		kills:        NewLabel(topLeft, fonts.HUD, white),
+		score:        NewLabel(topLeft.Below(line), fonts.HUD, white),
+		wave:         NewLabel(topLeft.Below(2*line), fonts.HUD, white),
+		intermission: NewTimer(topLeft.Below(3*line), fonts.HUD, gold, 160),
+		combo:        NewLabel(topLeft.Below(4*line+10), fonts.HUD, gold),
+		crit:         NewLabel(topLeft.Below(5*line+10), fonts.HUD, gold),
+		announcement: NewLabel(Layout{Anchor: AnchorTop, Y: screenHeight / 4}, fonts.Large, gold),
+	}
+	h.crit.SetText("Parry! Critical hits!")
+
+	// Health and stamina at the bottom center, stamina nearest the edge
+	h.stamina = NewBar(Layout{Anchor: AnchorBottom, Y: 36}, 300, 8, 1, color.RGBA{60, 50, 0, 255}, color.RGBA{240, 200, 40, 255})
+	_, staminaHeight := h.stamina.Size()
+	h.health = NewBar(Layout{Anchor: AnchorBottom, Y: 36 + staminaHeight + 4}, 300, 20, 2, color.RGBA{100, 0, 0, 255}, color.RGBA{0, 220, 0, 255})
+	_, healthHeight := h.health.Size()
+	h.healthLabel = NewLabel(Layout{Anchor: AnchorBottom, Y: 36 + staminaHeight + 4 + healthHeight + 4}, fonts.HUD, white)
+	h.levelUpWait = NewLabel(h.healthLabel.Layout.Below(-line), fonts.HUD, gold)
+	h.xp = NewBar(Layout{Anchor: AnchorBottom, Y: 20}, 300, 6, 1, color.RGBA{0, 30, 70, 255}, color.RGBA{80, 160, 255, 255})
+
+	// The level-up screen: cards across the middle of a darkened screen
+	h.levelUpShade = NewBar(Layout{Anchor: AnchorCenter}, screenWidth, screenHeight, 0, color.RGBA{0, 0, 0, 160}, color.RGBA{})
+	h.levelUpTitle = NewLabel(Layout{Anchor: AnchorCenter, Y: -upgradeCardHeight}, fonts.Large, gold)
+	h.levelUpTitle.SetText("Level Up!")
+	h.levelUpHint = NewLabel(Layout{Anchor: AnchorCenter, Y: upgradeCardHeight/2 + line}, fonts.HUD, white)
+	h.levelUpHint.SetText("Left and right to choose, attack to pick")
+	for i := range h.upgradeCards {
+		card := Layout{Anchor: AnchorCenter}
+		h.upgradeCards[i] = upgradeCard{
+			frame:       NewBar(card, upgradeCardWidth, upgradeCardHeight, 3, color.RGBA{40, 35, 30, 255}, color.RGBA{}),
+			highlight:   NewBar(card, upgradeCardWidth+12, upgradeCardHeight+12, 0, gold, gold),
+			name:        NewLabel(card.Below(-line), fonts.HUD, gold),
+			description: NewLabel(card, fonts.HUD, white),
+			stacks:      NewLabel(card.Below(line), fonts.HUD, color.RGBA{170, 170, 170, 255}),
+		}
+	}
+
+	// The boss bar spans the top center, with the name above it
+	h.bossName = NewLabel(Layout{Anchor: AnchorTop, Y: 20}, fonts.HUD, white)
+	h.bossBar = NewBar(Layout{Anchor: AnchorTop, Y: 24 + line}, 800, 16, 2, color.RGBA{80, 0, 0, 255}, color.RGBA{200, 30, 30, 255})
+
+	// Buffs in a row along the bottom left, each icon above its timer
+	const buffSpacing = pickupSize*hudIconScale + 24
+	for i := 0; i < hudBuffSlots; i++ {
+		timer := Layout{Anchor: AnchorBottomLeft, X: 20 + float64(i)*buffSpacing, Y: 40}
+		h.buffTimers[i] = NewTimer(timer, fonts.HUD, white, pickupSize*hudIconScale)
+		h.buffIcons[i] = &Icon{Layout: timer.Below(-line), Scale: hudIconScale}
+	}
+
+	// The weapon in hand in the bottom right corner, named above its icon
+	weapon := Layout{Anchor: AnchorBottomRight, X: 20, Y: 20}
+	h.weaponIcon = &Icon{Layout: weapon, Scale: hudIconScale}
+	h.weaponName = NewLabel(weapon.Below(-(pickupSize*hudIconScale + 6)), fonts.HUD, white)
+
+	// The title and game-over screens: headings above the score table, the prompt below it
+	h.screenShade = NewBar(Layout{Anchor: AnchorCenter}, screenWidth, screenHeight, 0, color.RGBA{0, 0, 0, 200}, color.RGBA{})
+	h.titleLabel = NewLabel(Layout{Anchor: AnchorTop, Y: 100}, fonts.Large, gold)
+	h.titleLabel.SetText("Orc Slaughter")
+	h.gameOverLabel = NewLabel(Layout{Anchor: AnchorTop, Y: 100}, fonts.Large, color.RGBA{220, 40, 40, 255})
+	h.gameOverLabel.SetText("Game Over")
+	h.gameOverRun = NewLabel(Layout{Anchor: AnchorTop, Y: 180}, fonts.HUD, white)
+	h.gameOverPlace = NewLabel(Layout{Anchor: AnchorTop, Y: 180 + line}, fonts.HUD, gold)
+	h.scores = NewScoreTable(Layout{Anchor: AnchorTop, Y: 200 + 3*line}, fonts.HUD, line)
+	h.titlePrompt = NewLabel(Layout{Anchor: AnchorBottom, Y: 120}, fonts.HUD, white)
+	h.gameOverPrompt = NewLabel(Layout{Anchor: AnchorBottom, Y: 120}, fonts.HUD, white)
+	h.gameOverPrompt.SetText("Press attack to quit")
+
+	return h
+}
+
+// Draw draws the HUD for the game's current state
+func (h *HUD) Draw(screen *ebiten.Image, g *Game) {
+	h.kills.SetText(fmt.Sprintf("Orcs Killed: %d", g.orcsKilled))
+	h.kills.Draw(screen, 1)
+	h.score.SetText(fmt.Sprintf("Score: %d", g.score))
+	h.score.Draw(screen, 1)
+	h.wave.SetText(fmt.Sprintf("Wave: %d", g.director.WaveNumber()))
+	h.wave.Draw(screen, 1)
+
+	// Count down to the next wave between waves
+	if g.director.InIntermission() {
+		h.intermission.Draw(screen, "Next wave in", g.director.IntermissionRemaining(), g.director.IntermissionDuration())
+	}
+
+	if g.comboHits >= 2 {
+		h.combo.SetText(fmt.Sprintf("%d Hit Combo!", g.comboHits))
+		h.combo.Draw(screen, 1)
+	}
+
+	// Let the player know a parry opened the critical-hit window
+	if g.critTimer > 0 {
+		h.crit.Draw(screen, 1)
+	}
+
+	if g.boss != nil {
+		h.bossName.SetText(g.boss.def.Name)
+		h.bossName.Draw(screen, 1)
+		h.bossBar.Draw(screen, float64(g.boss.GetHealth())/float64(g.boss.maxHealth))
+	}
+
+	if announcement := g.director.Announcement(); announcement != "" {
+		h.announcement.SetText(announcement)
+		h.announcement.Draw(screen, 1)
+	}
+
+	healthPercent := math.Max(0, g.playerHealth/g.maxHealth())
+	h.health.Draw(screen, healthPercent)
+	h.healthLabel.SetText(fmt.Sprintf("Level %d  Health: %.0f%%", g.playerLevel, healthPercent*100))
+	h.healthLabel.Draw(screen, 1)
+	h.xp.Draw(screen, g.xp/g.upgradeConfig.XPToLevel(g.playerLevel))
+	if g.pendingLevelUps > 0 && g.upgradeChoices == nil {
+		text := "Level up! Choose an upgrade after this wave"
+		if g.pendingLevelUps > 1 {
+			text = fmt.Sprintf("%d level-ups! Choose upgrades after this wave", g.pendingLevelUps)
+		}
+		h.levelUpWait.SetText(text)
+		h.levelUpWait.Draw(screen, 1)
+	}
+
+	h.stamina.Color = color.RGBA{240, 200, 40, 255}
+	if g.stamina < g.tuning.Player.Dodge.StaminaCost {
+		h.stamina.Color = color.RGBA{140, 110, 30, 255} // Dimmed while there isn't enough for a roll
+	}
+	h.stamina.Draw(screen, g.stamina/g.tuning.Player.Stamina.Max)
+
+	for i, buff := range g.buffs.Active() {
+		if i == hudBuffSlots {
+			break
+		}
+		// Fade the icon over the last two seconds as a warning
+		h.buffIcons[i].Image = g.pickupIcons[buff.Icon]
+		h.buffIcons[i].Draw(screen, math.Min(1, 0.4+0.3*buff.Remaining))
+		h.buffTimers[i].Draw(screen, "", buff.Remaining, buff.Duration)
+	}
+
+	weapon := g.weapon()
+	h.weaponIcon.Image = g.pickupIcons[weapon.Icon]
+	h.weaponIcon.Draw(screen, 1)
+	h.weaponName.SetText(weapon.Name)
+	h.weaponName.Draw(screen, 1)
+
+	if g.upgradeChoices != nil {
+		h.drawLevelUp(screen, g)
+	}
+}
+
+// DrawTitle draws the title screen over the waiting level
+func (h *HUD) DrawTitle(screen *ebiten.Image, g *Game) {
+	h.screenShade.Draw(screen, 0)
+	h.titleLabel.Draw(screen, 1)
+	h.scores.Draw(screen, g.scores.Runs, -1)
+	h.titlePrompt.SetText(fmt.Sprintf("%s awaits. Press attack to fight, pause for settings", g.level.Name))
+	h.titlePrompt.Draw(screen, 1)
+}
+
+// DrawGameOver draws how the run went and where it placed on the score table
+func (h *HUD) DrawGameOver(screen *ebiten.Image, g *Game) {
+	h.screenShade.Draw(screen, 0)
+	h.gameOverLabel.Draw(screen, 1)
+	run := g.lastRun
+	h.gameOverRun.SetText(fmt.Sprintf("Score %d   Kills %d   Wave %d   Survived %s   Seed %d",
+		run.Score, run.Kills, run.Wave, formatDuration(run.SurvivalTime), run.Seed))
+	h.gameOverRun.Draw(screen, 1)
+	if g.runPlace >= 0 {
+		h.gameOverPlace.SetText(fmt.Sprintf("New high score! #%d", g.runPlace+1))
+		h.gameOverPlace.Draw(screen, 1)
+	}
+	h.scores.Draw(screen, g.scores.Runs, g.runPlace)
+	h.gameOverPrompt.Draw(screen, 1)
+}
+
+// scoreColumns are the high score table's headings, centered on an offset
+// from the middle of the screen
+var scoreColumns = [...]struct {
+	title string
+	x     float64
+}{
+	{"#", -520}, {"Score", -420}, {"Kills", -310}, {"Wave", -210},
+	{"Time", -110}, {"Level", 30}, {"Date", 210}, {"Seed", 420},
+}
+
+// ScoreTable is the high score table, a heading row over one row per run
+type ScoreTable struct {
+	header [len(scoreColumns)]*Label
+	rows   [maxHighScores][len(scoreColumns)]*Label
+	empty  *Label
+}
+
+// NewScoreTable creates a score table with its heading at layout and rows
+// line pixels apart
+func NewScoreTable(layout Layout, f Font, line float64) *ScoreTable {
+	t := &ScoreTable{}
+	for i, column := range scoreColumns {
+		cell := layout
+		cell.X += column.x
+		t.header[i] = NewLabel(cell, f, color.RGBA{170, 170, 170, 255})
+		t.header[i].SetText(column.title)
+		for row := range t.rows {
+			t.rows[row][i] = NewLabel(cell.Below(float64(row+1)*line), f, color.RGBA{255, 255, 255, 255})
+		}
+	}
+	t.empty = NewLabel(layout.Below(line), f, color.RGBA{170, 170, 170, 255})
+	t.empty.SetText("No runs yet. Go make history.")
+	return t
+}
+
+// Draw draws the runs, picking out the one at highlight in gold (-1 for none)
+func (t *ScoreTable) Draw(screen *ebiten.Image, runs []RunRecord, highlight int) {
+	for _, label := range t.header {
+		label.Draw(screen, 1)
+	}
+	if len(runs) == 0 {
+		t.empty.Draw(screen, 1)
+		return
+	}
+
+	for i, run := range runs[:min(len(runs), len(t.rows))] {
+		cells := &t.rows[i]
+		cells[0].SetText(fmt.Sprintf("%d", i+1))
+		cells[1].SetText(fmt.Sprintf("%d", run.Score))
+		cells[2].SetText(fmt.Sprintf("%d", run.Kills))
+		cells[3].SetText(fmt.Sprintf("%d", run.Wave))
+		cells[4].SetText(formatDuration(run.SurvivalTime))
+		cells[5].SetText(run.Mode)
+		cells[6].SetText(run.Date.Local().Format("2006-01-02"))
+		cells[7].SetText(fmt.Sprintf("%d", run.Seed))
+
+		rowColor := color.RGBA{255, 255, 255, 255}
+		if i == highlight {
+			rowColor = color.RGBA{255, 220, 80, 255}
+		}
+		for _, cell := range cells {
+			cell.Color = rowColor
+			cell.Draw(screen, 1)
+		}
+	}
+}
+
+// drawLevelUp draws the upgrades on offer as a row of cards, the selected one highlighted
+func (h *HUD) drawLevelUp(screen *ebiten.Image, g *Game) {
+	h.levelUpShade.Draw(screen, 0)
+	h.levelUpTitle.Draw(screen, 1)
+	h.levelUpHint.Draw(screen, 1)
+
+	count := min(len(g.upgradeChoices), len(h.upgradeCards))
+	for i, name := range g.upgradeChoices[:count] {
+		upgrade := g.upgradeConfig.Upgrades[name]
+		if upgrade == nil {
+			continue // Removed from the data by a reload
+		}
+		card := &h.upgradeCards[i]
+
+		// Center the row of cards on the screen
+		x := (float64(i) - float64(count-1)/2) * (upgradeCardWidth + upgradeCardGap)
+		card.frame.Layout.X = x
+		card.highlight.Layout.X = x
+		card.name.Layout.X = x
+		card.description.Layout.X = x
+		card.stacks.Layout.X = x
+
+		if i == g.upgradeSelected {
+			card.highlight.Draw(screen, 1)
+		}
+		card.frame.Draw(screen, 0)
+		card.name.SetText(upgrade.Name)
+		card.name.Draw(screen, 1)
+		card.description.SetText(upgrade.Description)
+		card.description.Draw(screen, 1)
+		stacks := fmt.Sprintf("Picked %d", g.upgradeStacks[name])
+		if upgrade.MaxStacks > 0 {
+			stacks = fmt.Sprintf("Picked %d of %d", g.upgradeStacks[name], upgrade.MaxStacks)
+		}
+		card.stacks.SetText(stacks)
+		card.stacks.Draw(screen, 1)
+	}
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Action is a logical player action that physical inputs are bound to
+type Action int
+
+const (
+	ActionMoveLeft Action = iota
+	ActionMoveRight
+	ActionAttack
+	ActionDodge
+	ActionPause
+	ActionBlock
+	ActionMoveUp
+	ActionMoveDown
+	actionCount // Number of actions, keep last
+)
+
+// actionNames are the identifiers used for actions in the settings file
+var actionNames = [actionCount]string{
+	ActionMoveLeft:  "moveLeft",
+	ActionMoveRight: "moveRight",
+	ActionAttack:    "attack",
+	ActionDodge:     "dodge",
+	ActionPause:     "pause",
+	ActionBlock:     "block",
+	ActionMoveUp:    "moveUp",
+	ActionMoveDown:  "moveDown",
+}
+
+// actionLabels are the human-readable action names shown in menus
+var actionLabels = [actionCount]string{
+	ActionMoveLeft:  "Move Left",
+	ActionMoveRight: "Move Right",
+	ActionAttack:    "Attack",
+	ActionDodge:     "Dodge",
+	ActionPause:     "Pause",
+	ActionBlock:     "Block",
+	ActionMoveUp:    "Move Up",
+	ActionMoveDown:  "Move Down",
+}
+
+// String returns the settings identifier of the action
+func (a Action) String() string {
+	if a < 0 || a >= actionCount {
+		return fmt.Sprintf("Action(%d)", int(a))
+	}
+	return actionNames[a]
+}
+
+// MarshalText implements encoding.TextMarshaler so actions can be used as JSON map keys
+func (a Action) MarshalText() ([]byte, error) {
+	if a < 0 || a >= actionCount {
+		return nil, fmt.Errorf("invalid action %d", int(a))
+	}
+	return []byte(actionNames[a]), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (a *Action) UnmarshalText(text []byte) error {
+	for i, name := range actionNames {
+		if name == string(text) {
+			*a = Action(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown action %q", text)
+}
+
+// BindingKind identifies which physical device a binding refers to
+type BindingKind int
+
+const (
+	BindingKey BindingKind = iota
+	BindingGamepadButton
+	BindingGamepadAxis
+)
+
+// Binding maps a single physical input to an action.
+// In the settings file bindings are written as "Key:Space",
+// "Button:RightBottom" or "Axis:LeftStickHorizontal-".
+type Binding struct {
+	Kind     BindingKind
+	Key      ebiten.Key
+	Button   ebiten.StandardGamepadButton
+	Axis     ebiten.StandardGamepadAxis
+	Positive bool // For axis bindings, whether the positive direction triggers the action
+}
+
+// KeyBinding returns a keyboard binding
+func KeyBinding(key ebiten.Key) Binding {
+	return Binding{Kind: BindingKey, Key: key}
+}
+
+// ButtonBinding returns a standard-layout gamepad button binding
+func ButtonBinding(button ebiten.StandardGamepadButton) Binding {
+	return Binding{Kind: BindingGamepadButton, Button: button}
+}
+
+// AxisBinding returns a standard-layout gamepad axis binding for one direction
+func AxisBinding(axis ebiten.StandardGamepadAxis, positive bool) Binding {
+	return Binding{Kind: BindingGamepadAxis, Axis: axis, Positive: positive}
+}
+
+// gamepadButtonNames are the settings identifiers for standard gamepad buttons
+var gamepadButtonNames = map[ebiten.StandardGamepadButton]string{
+	ebiten.StandardGamepadButtonRightBottom:      "RightBottom",
+	ebiten.StandardGamepadButtonRightRight:       "RightRight",
+	ebiten.StandardGamepadButtonRightLeft:        "RightLeft",
+	ebiten.StandardGamepadButtonRightTop:         "RightTop",
+	ebiten.StandardGamepadButtonFrontTopLeft:     "FrontTopLeft",
+	ebiten.StandardGamepadButtonFrontTopRight:    "FrontTopRight",
+	ebiten.StandardGamepadButtonFrontBottomLeft:  "FrontBottomLeft",
+	ebiten.StandardGamepadButtonFrontBottomRight: "FrontBottomRight",
+	ebiten.StandardGamepadButtonCenterLeft:       "CenterLeft",
+	ebiten.StandardGamepadButtonCenterRight:      "CenterRight",
+	ebiten.StandardGamepadButtonLeftStick:        "LeftStick",
+	ebiten.StandardGamepadButtonRightStick:       "RightStick",
+	ebiten.StandardGamepadButtonLeftTop:          "LeftTop",
+	ebiten.StandardGamepadButtonLeftBottom:       "LeftBottom",
+	ebiten.StandardGamepadButtonLeftLeft:         "LeftLeft",
+	ebiten.StandardGamepadButtonLeftRight:        "LeftRight",
+	ebiten.StandardGamepadButtonCenterCenter:     "CenterCenter",
+}
+
+// gamepadAxisNames are the settings identifiers for standard gamepad axes
+var gamepadAxisNames = map[ebiten.StandardGamepadAxis]string{
+	ebiten.StandardGamepadAxisLeftStickHorizontal:  "LeftStickHorizontal",
+	ebiten.StandardGamepadAxisLeftStickVertical:    "LeftStickVertical",
+	ebiten.StandardGamepadAxisRightStickHorizontal: "RightStickHorizontal",
+	ebiten.StandardGamepadAxisRightStickVertical:   "RightStickVertical",
+}
+
+// String returns the binding in its settings file form
+func (b Binding) String() string {
+	switch b.Kind {
+	case BindingKey:
+		return "Key:" + b.Key.String()
+	case BindingGamepadButton:
+		return "Button:" + gamepadButtonNames[b.Button]
+	case BindingGamepadAxis:
+		sign := "-"
+		if b.Positive {
+			sign = "+"
+		}
+		return "Axis:" + gamepadAxisNames[b.Axis] + sign
+	}
+	return fmt.Sprintf("Binding(%d)", int(b.Kind))
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (b Binding) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (b *Binding) UnmarshalText(text []byte) error {
+	kind, name, ok := strings.Cut(string(text), ":")
+	if !ok {
+		return fmt.Errorf("invalid binding %q", text)
+	}
+
+	switch kind {
+	case "Key":
+		var key ebiten.Key
+		if err := key.UnmarshalText([]byte(name)); err != nil {
+			return fmt.Errorf("invalid key binding %q: %w", text, err)
+		}
+		*b = KeyBinding(key)
+		return nil
+	case "Button":
+		for button, buttonName := range gamepadButtonNames {
+			if buttonName == name {
+				*b = ButtonBinding(button)
+				return nil
+			}
+		}
+	case "Axis":
+		if len(name) > 1 {
+			sign := name[len(name)-1]
+			for axis, axisName := range gamepadAxisNames {
+				if axisName == name[:len(name)-1] && (sign == '+' || sign == '-') {
+					*b = AxisBinding(axis, sign == '+')
+					return nil
+				}
+			}
+		}
+	}
+
+	return fmt.Errorf("invalid binding %q", text)
+}
+
+// Label returns a short human-readable description of the binding for menus
+func (b Binding) Label() string {
+	switch b.Kind {
+	case BindingKey:
+		return b.Key.String()
+	case BindingGamepadButton:
+		return "Pad " + gamepadButtonNames[b.Button]
+	case BindingGamepadAxis:
+		return "Pad " + strings.TrimPrefix(b.String(), "Axis:")
+	}
+	return b.String()
+}
+
+// isGamepad reports whether the binding refers to a gamepad rather than the keyboard
+func (b Binding) isGamepad() bool {
+	return b.Kind != BindingKey
+}
+
+// AttackMode controls how holding the attack input behaves
+type AttackMode string
+
+const (
+	AttackModePress AttackMode = "press" // Each press attacks once
+	AttackModeHold  AttackMode = "hold"  // Holding the input keeps attacking
+)
+
+// inputBufferTicks is how long a press is remembered if it can't be acted on
+// immediately (e.g. attack pressed during the last frames of a hurt animation)
+const inputBufferTicks = 8
+
+// ActionSet is a bitmask of actions held during a single tick
+type ActionSet uint16
+
+// Has reports whether the action is in the set
+func (s ActionSet) Has(a Action) bool {
+	return s&(1<<a) != 0
+}
+
+// With returns the set with the action added
+func (s ActionSet) With(a Action) ActionSet {
+	return s | 1<<a
+}
+
+// InputManager turns physical device state into action state each tick
+type InputManager struct {
+	settings *InputSettings
+
+	held     ActionSet
+	prevHeld ActionSet
+	buffered [actionCount]int // Remaining ticks a press stays buffered
+
+	gamepadIDs []ebiten.GamepadID
+}
+
+// NewInputManager creates an input manager reading bindings from settings
+func NewInputManager(settings *InputSettings) *InputManager {
+	return &InputManager{settings: settings}
+}
+
+// Poll reads every bound device and returns the set of actions currently held
+func (m *InputManager) Poll() ActionSet {
+	m.gamepadIDs = ebiten.AppendGamepadIDs(m.gamepadIDs[:0])
+
+	var held ActionSet
+	for action, bindings := range m.settings.Bindings {
+		for _, binding := range bindings {
+			if m.isBindingActive(binding) {
+				held = held.With(action)
+				break
+			}
+		}
+	}
+	return held
+}
+
+// isBindingActive reports whether a binding's input is currently held on any device
+func (m *InputManager) isBindingActive(b Binding) bool {
+	switch b.Kind {
+	case BindingKey:
+		return ebiten.IsKeyPressed(b.Key)
+	case BindingGamepadButton:
+		for _, id := range m.gamepadIDs {
+			if ebiten.IsStandardGamepadLayoutAvailable(id) && ebiten.IsStandardGamepadButtonPressed(id, b.Button) {
+				return true
+			}
+		}
+	case BindingGamepadAxis:
+		for _, id := range m.gamepadIDs {
+			if !ebiten.IsStandardGamepadLayoutAvailable(id) {
+				continue
+			}
+			value := ebiten.StandardGamepadAxisValue(id, b.Axis)
+			if b.Positive && value >= m.settings.AxisDeadzone {
+				return true
+			}
+			if !b.Positive && value <= -m.settings.AxisDeadzone {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Update advances the action state by one tick using the given held actions
+func (m *InputManager) Update(held ActionSet) {
+	m.prevHeld = m.held
+	m.held = held
+
+	for a := Action(0); a < actionCount; a++ {
+		if m.buffered[a] > 0 {
+			m.buffered[a]--
+		}
+		if m.JustPressed(a) {
+			m.buffered[a] = inputBufferTicks
+		}
+	}
+}
+
+// Held returns the actions held this tick
+func (m *InputManager) Held() ActionSet {
+	return m.held
+}
+
+// IsHeld reports whether the action is currently held
+func (m *InputManager) IsHeld(a Action) bool {
+	return m.held.Has(a)
+}
+
+// JustPressed reports whether the action went from released to held this tick
+func (m *InputManager) JustPressed(a Action) bool {
+	return m.held.Has(a) && !m.prevHeld.Has(a)
+}
+
+// ConsumePress returns true if the action was pressed within the buffer window
+// and clears the buffered press so it only triggers once
+func (m *InputManager) ConsumePress(a Action) bool {
+	if m.buffered[a] > 0 {
+		m.buffered[a] = 0
+		return true
+	}
+	return false
+}
+
+// Triggered reports whether an action that can repeat should fire this tick.
+// In hold mode it fires while held; in press mode it only fires on a fresh press.
+func (m *InputManager) Triggered(a Action, mode AttackMode) bool {
+	if mode == AttackModeHold && m.IsHeld(a) {
+		m.buffered[a] = 0
+		return true
+	}
+	return m.ConsumePress(a)
+}
+
+// Reset clears all held and buffered state, e.g. when returning from a menu
+func (m *InputManager) Reset(held ActionSet) {
+	m.held = held
+	m.prevHeld = held
+	m.buffered = [actionCount]int{}
+}
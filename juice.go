@@ -0,0 +1,123 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// flashShaderSource is a Kage shader that turns a sprite towards white by
+// Amount (0 to 1) while keeping its outline and transparency
+var flashShaderSource = []byte(`//kage:unit pixels
+
+package main
+
+var Amount float
+
+func Fragment(dstPos vec4, srcPos vec2, color vec4) vec4 {
+	c := imageSrc0At(srcPos) * color
+	// Colors are premultiplied, so full white at alpha a is (a, a, a, a)
+	return vec4(mix(c.rgb, vec3(c.a), Amount), c.a)
+}
+`)
+
+// Juice makes hits feel heavy: a brief freeze on impact (hit-stop), camera
+// shake, and slow motion on the final kill of a wave. Each effect can be
+// turned off in the accessibility settings.
+//
+// Hit-stop and slow motion hold the simulation for whole frames rather than
+// changing its timestep, the same way pausing does, so runs play out exactly
+// the same and replays stay valid whatever the settings.
+type Juice struct {
+	tuning   *Tuning
+	settings *Settings
+	camera   *Camera
+
+	hitStop    float64 // Seconds of freeze left
+	slowMotion float64 // Seconds of slow motion left
+	tickCredit float64 // Simulation ticks owed during slow motion
+}
+
+// NewJuice creates the feedback effects for a game
+func NewJuice(tuning *Tuning, settings *Settings, camera *Camera) *Juice {
+	return &Juice{tuning: tuning, settings: settings, camera: camera}
+}
+
+// HitStop freezes the action for duration seconds, unless a longer freeze is already running
+func (j *Juice) HitStop(duration float64) {
+	if j.settings.Accessibility.HitStop {
+		j.hitStop = max(j.hitStop, duration)
+	}
+}
+
+// Shake adds trauma to the camera
+func (j *Juice) Shake(trauma float64) {
+	if j.settings.Accessibility.ScreenShake {
+		j.camera.AddTrauma(trauma)
+	}
+}
+
+// SlowMotion slows the game down for a moment
+func (j *Juice) SlowMotion() {
+	if j.settings.Accessibility.SlowMotion {
+		j.slowMotion = j.tuning.Juice.SlowMotionDuration
+	}
+}
+
+// HoldFrame is called once per frame and reports whether the simulation should
+// sit this frame out. It also advances the shake, which keeps going during a freeze.
+func (j *Juice) HoldFrame() bool {
+	const dt = 1.0 / 60.0 // Assuming 60 FPS
+	j.camera.UpdateShake()
+
+	if j.hitStop > 0 {
+		j.hitStop -= dt
+		return true
+	}
+
+	if j.slowMotion > 0 {
+		// Run a tick whenever enough slowed time has built up for one
+		j.slowMotion -= dt
+		j.tickCredit += j.tuning.Juice.SlowMotionScale
+		if j.tickCredit < 1 {
+			return true
+		}
+		j.tickCredit--
+		return false
+	}
+
+	j.tickCredit = 0
+	return false
+}
+
+// flashShader returns the hit flash shader, or nil if hit flashes are turned off
+func (g *Game) flashShader() *ebiten.Shader {
+	if !g.settings.Accessibility.HitFlash {
+		return nil
+	}
+	return g.hitFlashShader
+}
+
+// applyJuice reacts to combat events with hit-stop, shake and flashes
+func (g *Game) applyJuice(event CombatEvent) {
+	juice := g.tuning.Juice
+	switch event.Kind {
+	case EventEnemyHit:
+		g.juice.HitStop(juice.HitStop)
+		g.juice.Shake(juice.HitTrauma)
+	case EventEnemyKilled:
+		g.juice.HitStop(juice.KillHitStop)
+		g.juice.Shake(juice.KillTrauma)
+		if g.aliveOrcCount() == 0 && g.director.WaveFullySpawned() {
+			// The last enemy of the wave just fell
+			g.juice.SlowMotion()
+		}
+	case EventPlayerHit:
+		g.juice.HitStop(juice.HurtHitStop)
+		g.juice.Shake(juice.HurtTrauma)
+		g.hitFlash = juice.FlashDuration
+	case EventBlock:
+		g.juice.Shake(juice.BlockTrauma)
+	case EventParry:
+		g.juice.HitStop(juice.ParryHitStop)
+		g.juice.Shake(juice.ParryTrauma)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// levelsPath is the location of the level data file
+const levelsPath = "assets/levels.json"
+
+// Level is an arena to fight in. The world is centered on x = 0, so a level
+// reaches Width/2 pixels either side of it.
+type Level struct {
+	Name       string            `json:"name"`
+	Width      float64           `json:"width"`      // World width in pixels, at least the width of the screen
+	Background []BackgroundLayer `json:"background"` // Parallax layers, furthest first
+}
+
+// LevelConfig is every level in levelsPath
+type LevelConfig struct {
+	Levels []Level `json:"levels"`
+}
+
+// LoadLevels reads and validates the level data file
+func LoadLevels(path string) (*LevelConfig, error) {
+	config := &LevelConfig{}
+	if err := decodeDataFile(path, "level", config, config.Validate); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate checks that every level is playable
+func (c *LevelConfig) Validate() error {
+	if len(c.Levels) == 0 {
+		return errors.New("no levels defined")
+	}
+
+	var errs []error
+	seen := map[string]bool{}
+	for i, level := range c.Levels {
+		if level.Name == "" {
+			errs = append(errs, fmt.Errorf("level %d: missing name", i+1))
+		} else if seen[level.Name] {
+			errs = append(errs, fmt.Errorf("level %d: duplicate name %q", i+1, level.Name))
+		}
+		seen[level.Name] = true
+
+		if level.Width < screenWidth {
+			errs = append(errs, fmt.Errorf("level %q: width must be at least the screen width (%d), got %v", level.Name, screenWidth, level.Width))
+		}
+		for j, layer := range level.Background {
+			if err := layer.Validate(); err != nil {
+				errs = append(errs, fmt.Errorf("level %q: background layer %d: %w", level.Name, j+1, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Find returns the level with the given name, or the first level if name is empty
+func (c *LevelConfig) Find(name string) (*Level, error) {
+	if name == "" {
+		return &c.Levels[0], nil
+	}
+	for i := range c.Levels {
+		if c.Levels[i].Name == name {
+			return &c.Levels[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown level %q", name)
+}
+
+// ClampX keeps a horizontal position inside the level
+func (l *Level) ClampX(x float64) float64 {
+	return max(-l.Width/2, min(l.Width/2, x))
+}
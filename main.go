@@ -1,20 +1,22 @@
 package main
 
 import (
-	"fmt"
+	"cmp"
+	"flag"
 	"image"
 	"image/color"
 	_ "image/png"
 	"log"
+	"math/rand/v2"
 	"os"
+	"slices"
+	"time"
 
 	"rpg_demo/aseprite"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/audio"
 	"github.com/hajimehoshi/ebiten/v2/audio/mp3"
-	"github.com/hajimehoshi/ebiten/v2/text"
-	"golang.org/x/image/font/basicfont"
 )
 
 const (
@@ -22,6 +24,13 @@ const (
 	screenHeight = 1024
 )
 
+// Mix levels for sound effects, relative to the effects volume setting
+const (
+	attackSoundMix = 1.0
+	orcHitSoundMix = 0.8 // Hits and deaths sit slightly below the attack swing
+	orcDieSoundMix = 0.8
+)
+
 // PlayerState represents the current state of the player
 type PlayerState int
 
@@ -34,30 +43,54 @@ const (
 
 // Game represents our game state
 type Game struct {
-	soldierSprite   *ebiten.Image
-	backgroundImage *ebiten.Image
-	asepriteFile    *aseprite.File
+	soldierSprite *ebiten.Image
+	asepriteFile  *aseprite.File
 
 	// Animation state
-	currentFrame     int
-	frameTimer       float64
-	frameDuration    float64 // in seconds
-	idleFrameStart   int
-	idleFrameEnd     int
-	walkFrameStart   int
-	walkFrameEnd     int
-	attackFrameStart int
-	attackFrameEnd   int
-	hurtFrameStart   int
-	hurtFrameEnd     int
+	currentFrame   int
+	frameTimer     float64
+	idleFrameStart int
+	idleFrameEnd   int
+	walkFrameStart int
+	walkFrameEnd   int
+	hurtFrameStart int
+	hurtFrameEnd   int
+	playerTags     map[string][2]int // Frame range of every tag in the soldier sprite, by name
 
 	// Movement and sprite state
 	positionX   float64
+	positionY   float64 // Depth in the arena, larger is closer to the camera
 	isWalking   bool
 	facingLeft  bool
-	walkSpeed   float64
 	isAttacking bool
 
+	// Attack combo
+	comboStep     int     // Index of the current (or last) combo step
+	comboQueued   bool    // Attack was pressed during the swing, chain into the next step
+	comboTimer    float64 // Time left to continue the combo after a swing ends
+	swingID       int     // Increments every swing so each swing hits an enemy once
+	comboHits     int     // Hits landed in a row, shown in the HUD
+	comboHitTimer float64 // Time left before the hit counter resets
+
+	// Dodge roll and invulnerability
+	isDodging     bool
+	dodgeTimer    float64 // Seconds into the current roll
+	dodgeCooldown float64 // Time left before the player can roll again
+	stamina       float64
+	staminaDelay  float64 // Time left before stamina starts regenerating
+	invulnTimer   float64 // Time left of post-hit invulnerability
+
+	// Blocking and parrying
+	isBlocking      bool
+	blockTimer      float64     // Seconds the block has been up; a hit early on is parried
+	parryCooldown   float64     // Time left after lowering the block before a fresh press can parry again
+	critTimer       float64     // Time left of the critical-hit window opened by a parry
+	guardSpark      CombatEvent // Latest block or parry, drawn as a spark
+	guardSparkTimer float64
+
+	// Combat events for audio and visual effects
+	events CombatEvents
+
 	// Player state
 	playerState     PlayerState
 	playerHealth    float64
@@ -68,6 +101,86 @@ type Game struct {
 	flashVisible    bool    // Whether player sprite is visible during flash
 	flashCount      int     // Number of flashes completed
 
+	// Balance values loaded from the tuning file
+	tuning *Tuning
+
+	// The arena being played, the view of it and its backdrop
+	level      *Level
+	camera     *Camera
+	background *Background
+
+	// Visual effects
+	particles      *ParticleSystem
+	combatText     *CombatText
+	juice          *Juice
+	hitFlashShader *ebiten.Shader
+	hitFlash       float64 // Time left of the player's white flash after taking damage
+
+	// Items dropped by enemies and the timed buffs they grant
+	pickupConfig *PickupConfig
+	pickupIcons  map[string]*ebiten.Image // By icon tag
+	pickups      []*Pickup
+	buffs        BuffManager
+
+	// Weapons the soldier can wield and the one in hand
+	weapons      *WeaponConfig
+	weaponName   string
+	shotSwing    int                      // Last swing a ranged weapon shot on, so each swing shoots once
+	weaponSounds map[string]*audio.Player // By sound file
+
+	// Experience and the upgrades picked on leveling up
+	upgradeConfig   *UpgradeConfig
+	stats           PlayerStats
+	xp              float64        // XP toward the next level
+	playerLevel     int            // Starts at 1
+	pendingLevelUps int            // Level-ups whose upgrade hasn't been offered yet
+	upgradeStacks   map[string]int // Times each upgrade has been picked
+	upgradeChoices  []string       // Upgrades on offer; the fight is paused while this is set
+	upgradeSelected int            // Index into upgradeChoices
+
+	// Text and the heads-up display
+	fonts *Fonts
+	hud   *HUD
+
+	// Settings
+	settings     *Settings
+	settingsPath string // Where settings are saved; empty if the config directory is unavailable
+
+	// Input and menus
+	input      *InputManager
+	menu       *SettingsMenu
+	pauseHeld  bool // Whether pause was held last tick, tracked outside the simulation input
+	attackHeld bool // Whether attack was held last tick, for the title and game-over screens
+	resetInput bool // Reset input state on the next simulation tick (set when leaving the menu)
+
+	// Actions held during frames the simulation skipped (hit-stop, slow motion),
+	// merged into the next tick
+	pendingInput ActionSet
+
+	// Determinism and replays
+	rng        *rand.Rand // All gameplay randomness must come from here
+	seed       uint64
+	ticks      uint64  // Simulation ticks since the run started
+	fightTicks uint64  // Ticks the fight ran, leaving out those paused for upgrade choices
+	recorder   *Replay // Non-nil while recording a replay
+	replay     *Replay // Non-nil while playing back a replay
+	replayTick int     // Index of the next replay tick to apply
+
+	// Dev builds reload data files while running
+	dataWatcher dataWatcher
+
+	// Run lifecycle
+	gameOver  bool // Player died and the death sequence finished
+	quit      bool // Player chose to quit from the menu
+	showTitle bool // The title screen is up and the run hasn't started
+	overTime  int  // Frames the game-over screen has been up, so a frantic attack doesn't skip it
+
+	// High scores
+	scores     *ScoreBoard
+	scoresPath string    // Where scores are saved; empty if the data directory is unavailable
+	lastRun    RunRecord // The run that just ended
+	runPlace   int       // Where the last run placed on the score board, or -1
+
 	// Audio
 	audioContext *audio.Context
 	musicPlayer  *audio.Player
@@ -75,101 +188,245 @@ type Game struct {
 	orcHitPlayer *audio.Player
 	orcDiePlayer *audio.Player
 
+	// Boss themes by file path, and the one playing instead of the soundtrack (if any)
+	bossMusic       map[string]*audio.Player
+	activeBossMusic *audio.Player
+
+	// Drawing
+	pixel *ebiten.Image // 1x1 white image, scaled and tinted to draw HUD bars
+
 	// Enemies and scoring
-	orcs          []*Orc  // Multiple orcs
-	orcPrevHealth int     // Track previous orc health to detect damage
-	orcsKilled    int     // Counter for killed orcs
-	spawnTimer    float64 // Timer for spawning new orcs
-	spawnInterval float64 // Time between spawns (decreases as game progresses)
+	orcs          []*Orc         // Multiple orcs
+	orcPrevHealth int            // Track previous orc health to detect damage
+	orcsKilled    int            // Counter for killed orcs
+	crowd         []float64      // Positions of living orcs, rebuilt every tick for the AI
+	crowdBodies   []CrowdBody    // Reused buffer for the orc separation pass
+	drawOrder     []*Orc         // Reused buffer for sorting orcs by depth when drawing
+	score         int            // Points from kills, worth each enemy's score value
+	enemies       *EnemyFactory  // Creates enemies from the enemy definition file
+	director      *SpawnDirector // Decides when and where enemies spawn
+	projectiles   []*Projectile  // Thrown weapons in flight
+	boss          *Orc           // The boss being fought, nil if there is none
+	bossPhase     int            // Last boss phase seen, to announce phase changes
 }
 
 // Update handles game logic updates
 func (g *Game) Update() error {
+	// During playback the recorded input drives the simulation and the menu is unavailable
+	var held ActionSet
+	if g.replay == nil {
+		held = g.input.Poll()
+
+		// Pause is handled outside the simulation so menu time never reaches replays
+		pausePressed := held.Has(ActionPause) && !g.pauseHeld
+		g.pauseHeld = held.Has(ActionPause)
+		attackPressed := held.Has(ActionAttack) && !g.attackHeld
+		g.attackHeld = held.Has(ActionAttack)
+
+		// The game-over screen stays up until the player leaves. The run is over,
+		// so nothing more reaches the simulation or a recording.
+		if g.gameOver {
+			g.overTime++
+			if g.overTime > gameOverInputDelay && (attackPressed || pausePressed) {
+				return ebiten.Termination
+			}
+			return nil
+		}
+
+		// The settings menu pauses the game while it is open
+		if g.menu.IsOpen() {
+			g.menu.Update(pausePressed)
+			if g.quit {
+				return ebiten.Termination
+			}
+			return nil
+		}
+		if pausePressed {
+			g.menu.Open()
+			return nil
+		}
+
+		// The run starts when the player leaves the title screen
+		if g.showTitle {
+			if attackPressed {
+				g.showTitle = false
+				g.resetInput = true // Don't let the press that started the run swing the sword
+			}
+			return nil
+		}
+	}
+
+	// Hit-stop and slow motion hold the simulation for whole frames. Like the
+	// menu they sit outside it, so held frames never reach replays. Input seen
+	// on them is kept for the next tick, so a quick tap isn't lost.
+	if g.juice.HoldFrame() {
+		g.pendingInput |= held
+		return nil
+	}
+
+	var entry ActionSet
+	if g.replay != nil {
+		if g.replayTick >= len(g.replay.Ticks) {
+			return ebiten.Termination
+		}
+		entry = g.replay.Ticks[g.replayTick]
+		g.replayTick++
+	} else {
+		entry = (held | g.pendingInput) &^ ActionSet(0).With(ActionPause)
+		g.pendingInput = 0
+		if g.resetInput {
+			entry |= inputResetFlag
+			g.resetInput = false
+		}
+	}
+
+	if g.recorder != nil {
+		g.recorder.Record(entry)
+	}
+	g.applyTickInput(entry)
+
+	// Data reloads would break determinism during playback, and a reload while
+	// recording would change the run in a way the replay can't reproduce
+	if g.replay == nil && g.recorder == nil {
+		g.watchDataFiles()
+	}
+
+	// Choosing an upgrade pauses the fight, though the tick still counts for replays
+	if g.updateProgression() {
+		g.ticks++
+		return nil
+	}
+
 	g.handlePlayerInput()
 	g.updatePlayerAnimation()
 	g.updatePlayerDeath()
 	g.updateOrcLogic()
+	g.updatePickups()
+	g.camera.Update(g.positionX, g.positionY)
+	g.background.Update()
+	g.particles.Update()
+	g.combatText.Update()
+	g.ticks++
+	g.fightTicks++
+
+	// Playback has no game-over screen and ends with the run
+	if g.gameOver && g.replay != nil {
+		return ebiten.Termination
+	}
 	return nil
 }
 
+// applyTickInput feeds one tick of input (live or recorded) to the input manager
+func (g *Game) applyTickInput(entry ActionSet) {
+	held := entry &^ inputResetFlag
+	if entry&inputResetFlag != 0 {
+		g.input.Reset(held)
+	} else {
+		g.input.Update(held)
+	}
+}
+
 // Draw handles rendering
 func (g *Game) Draw(screen *ebiten.Image) {
 	// Draw background first
-	if g.backgroundImage != nil {
-		screen.DrawImage(g.backgroundImage, &ebiten.DrawImageOptions{})
-	}
-
-	// Draw the soldier sprite (don't draw if flashing and currently invisible)
-	if g.soldierSprite != nil && !(g.playerState == PlayerStateDying && g.deathTimer <= 0 && !g.flashVisible) {
-		opts := &ebiten.DrawImageOptions{}
+	g.background.Draw(screen, g.camera)
 
-		// Scale the sprite 10x larger
-		const scale = 10.0
-		opts.GeoM.Scale(scale, scale)
+	// Pickups lie on the floor, under everyone
+	g.drawPickups(screen)
 
-		// Calculate sprite dimensions
-		spriteWidth := float64(g.soldierSprite.Bounds().Dx()) * scale
-		spriteHeight := float64(g.soldierSprite.Bounds().Dy()) * scale
+	// Draw the fighters back to front, so those nearer the camera overlap those behind
+	g.drawOrder = g.drawOrder[:0]
+	for _, orc := range g.orcs {
+		if orc != nil {
+			g.drawOrder = append(g.drawOrder, orc)
+		}
+	}
+	slices.SortStableFunc(g.drawOrder, func(a, b *Orc) int {
+		return cmp.Compare(a.positionY, b.positionY)
+	})
+	playerDrawn := false
+	for _, orc := range g.drawOrder {
+		if !playerDrawn && orc.positionY > g.positionY {
+			g.drawPlayer(screen)
+			playerDrawn = true
+		}
+		orc.Draw(screen, g.camera, g.flashShader(), g.settings.Accessibility.ReduceFlashing)
+	}
+	if !playerDrawn {
+		g.drawPlayer(screen)
+	}
 
-		// Calculate final position
-		finalX := (float64(screenWidth)-spriteWidth)/2 + g.positionX
-		finalY := (float64(screenHeight)-spriteHeight)/2 + float64(screenHeight)*0.2
+	// Draw projectiles in front of the characters
+	for _, p := range g.projectiles {
+		p.Draw(screen, g.pixel, g.camera)
+	}
 
-		// If facing left, flip around the center of the sprite
-		if g.facingLeft {
-			// Translate to center, flip, then translate back
-			opts.GeoM.Translate(-spriteWidth/2, -spriteHeight/2)
-			opts.GeoM.Scale(-1, 1)
-			opts.GeoM.Translate(spriteWidth/2, spriteHeight/2)
-		}
+	// Particles and sparks go over everything in the world
+	g.particles.Draw(screen, g.pixel, g.camera)
+	g.drawGuardSpark(screen)
+	g.combatText.DrawNumbers(screen, g.camera)
 
-		// Position the sprite at its final location
-		opts.GeoM.Translate(finalX, finalY)
+	// Draw the HUD, then the kill feed and kill streaks
+	g.hud.Draw(screen, g)
+	g.combatText.DrawHUD(screen)
 
-		screen.DrawImage(g.soldierSprite, opts)
+	// The title and game-over screens cover the HUD
+	if g.showTitle {
+		g.hud.DrawTitle(screen, g)
+	} else if g.gameOver && g.replay == nil {
+		g.hud.DrawGameOver(screen, g)
 	}
 
-	// Draw all orcs
-	for _, orc := range g.orcs {
-		if orc != nil {
-			orc.Draw(screen)
+	// Draw the pause menu on top of everything
+	g.menu.Draw(screen)
+}
+
+// drawPlayer draws the soldier sprite
+func (g *Game) drawPlayer(screen *ebiten.Image) {
+	// Don't draw if flashing and currently invisible
+	flashHidden := g.playerState == PlayerStateDying && g.deathTimer <= 0 && !g.flashVisible
+	// Blink while invulnerable after a hit
+	invulnBlink := g.invulnTimer > 0 && g.playerState != PlayerStateDying && int(g.invulnTimer*10)%2 == 1
+	if g.soldierSprite != nil && !((flashHidden || invulnBlink) && !g.settings.Accessibility.ReduceFlashing) {
+		opts := &ebiten.DrawImageOptions{}
+		if invulnBlink {
+			// Reduced flashing: fade instead of blinking
+			opts.ColorScale.ScaleAlpha(0.5)
+		}
+		if g.buffs.Has(BuffInvulnerable) {
+			opts.ColorScale.Scale(1.3, 1.15, 0.6, 1) // A golden sheen while nothing can hurt the player
+		}
+
+		// Scale the sprite 10x larger, flipped if facing left
+		if flash := g.flashShader(); flash != nil && g.hitFlash > 0 {
+			g.camera.DrawFlashingSprite(screen, g.soldierSprite, g.positionX, g.positionY, playerSpriteScale, g.facingLeft, opts, flash, g.hitFlash/g.tuning.Juice.FlashDuration)
+			return
 		}
+		g.camera.DrawSprite(screen, g.soldierSprite, g.positionX, g.positionY, playerSpriteScale, g.facingLeft, opts)
 	}
+}
 
-	// Draw kill counter in top-left corner
-	killText := fmt.Sprintf("Orcs Killed: %d", g.orcsKilled)
-	text.Draw(screen, killText, basicfont.Face7x13, 20, 30, color.RGBA{255, 255, 255, 255})
-
-	// Draw lifebar at bottom-center of screen
-	barWidth := 300.0
-	barHeight := 20.0
-	barX := (float64(screenWidth) - barWidth) / 2
-	barY := float64(screenHeight) - 60 // 60 pixels from bottom
-
-	// Draw background (dark red)
-	backgroundBar := ebiten.NewImage(int(barWidth), int(barHeight))
-	backgroundBar.Fill(color.RGBA{100, 0, 0, 255}) // Dark red
-	opts := &ebiten.DrawImageOptions{}
-	opts.GeoM.Translate(barX, barY)
-	screen.DrawImage(backgroundBar, opts)
-
-	// Draw health bar (green, proportional to health)
-	healthPercent := g.playerHealth / 100.0
-	if healthPercent < 0 {
-		healthPercent = 0
-	}
-	healthWidth := barWidth * healthPercent
-	if healthWidth > 0 {
-		healthBar := ebiten.NewImage(int(healthWidth), int(barHeight))
-		healthBar.Fill(color.RGBA{0, 255, 0, 255}) // Bright green
-		healthOpts := &ebiten.DrawImageOptions{}
-		healthOpts.GeoM.Translate(barX, barY)
-		screen.DrawImage(healthBar, healthOpts)
-	}
-
-	// Draw health text
-	healthText := fmt.Sprintf("Health: %.0f%%", g.playerHealth)
-	text.Draw(screen, healthText, basicfont.Face7x13, int(barX), int(barY-10), color.RGBA{255, 255, 255, 255})
+// applyAudioSettings updates every audio player's volume from the current settings
+func (g *Game) applyAudioSettings() {
+	audioSettings := g.settings.Audio
+	if g.musicPlayer != nil {
+		g.musicPlayer.SetVolume(audioSettings.EffectiveMusicVolume())
+	}
+	if g.activeBossMusic != nil {
+		g.activeBossMusic.SetVolume(audioSettings.EffectiveMusicVolume())
+	}
+	if g.attackPlayer != nil {
+		g.attackPlayer.SetVolume(audioSettings.EffectiveEffectsVolume(attackSoundMix))
+	}
+	for _, player := range g.weaponSounds {
+		player.SetVolume(audioSettings.EffectiveEffectsVolume(attackSoundMix))
+	}
+	if g.orcHitPlayer != nil {
+		g.orcHitPlayer.SetVolume(audioSettings.EffectiveEffectsVolume(orcHitSoundMix))
+	}
+	if g.orcDiePlayer != nil {
+		g.orcDiePlayer.SetVolume(audioSettings.EffectiveEffectsVolume(orcDieSoundMix))
+	}
 }
 
 // Layout returns the game's screen dimensions
@@ -194,11 +451,81 @@ func loadImageFromFile(filename string) (*ebiten.Image, error) {
 }
 
 func main() {
-	ebiten.SetWindowSize(screenWidth, screenHeight)
-	ebiten.SetWindowTitle("RPG Demo - Aseprite Loading")
+	seedFlag := flag.Uint64("seed", 0, "RNG seed for the run (0 picks one from the clock)")
+	recordPath := flag.String("record", "", "record the run's input to this replay file")
+	replayPath := flag.String("replay", "", "play back a replay file and verify its final state")
+	replaySpeed := flag.Int("replay-speed", 1, "playback speed multiplier for -replay")
+	levelName := flag.String("level", "", "name of the level to play, from "+levelsPath+" (default: the first level)")
+	flag.Parse()
 
 	game := &Game{}
 
+	// Load persistent settings (missing or corrupt files fall back to defaults)
+	path, err := settingsPath()
+	if err != nil {
+		log.Printf("Settings will not be saved: %v", err)
+		game.settings = DefaultSettings()
+	} else {
+		var needsSave bool
+		game.settings, needsSave = LoadSettings(path)
+		game.settingsPath = path
+		if needsSave {
+			if err := game.settings.Save(path); err != nil {
+				log.Printf("Failed to save settings: %v", err)
+			}
+		}
+	}
+
+	// Load the high score table (missing, corrupt or tampered files start a new one)
+	game.runPlace = -1
+	path, err = scoresPath()
+	if err != nil {
+		log.Printf("High scores will not be saved: %v", err)
+		game.scores = &ScoreBoard{}
+	} else {
+		game.scores = LoadScoreBoard(path)
+		game.scoresPath = path
+	}
+
+	// Set up the seeded RNG, either from a replay or for a fresh run
+	if *replayPath != "" {
+		replay, err := LoadReplay(*replayPath)
+		if err != nil {
+			log.Fatalf("Failed to load replay: %v", err)
+		}
+		game.replay = replay
+		game.seed = replay.Seed
+		*levelName = replay.Level
+		game.settings.Input.AttackMode = replay.AttackMode // Not saved: the menu is unavailable during playback
+		if *replaySpeed > 1 {
+			// The simulation uses a fixed timestep, so running more ticks per second only speeds it up
+			ebiten.SetTPS(ebiten.DefaultTPS * *replaySpeed)
+		}
+		log.Printf("Playing back replay %s: seed %d, %d ticks", *replayPath, replay.Seed, len(replay.Ticks))
+	} else {
+		game.seed = *seedFlag
+		if game.seed == 0 {
+			game.seed = uint64(time.Now().UnixNano())
+		}
+		if *recordPath != "" {
+			game.recorder = &Replay{Seed: game.seed, Level: *levelName, AttackMode: game.settings.Input.AttackMode}
+		}
+		log.Printf("Starting run with seed %d", game.seed)
+		game.showTitle = true
+	}
+	game.rng = rand.New(rand.NewPCG(game.seed, game.seed))
+
+	game.input = NewInputManager(&game.settings.Input)
+	game.menu = NewSettingsMenu(game)
+
+	// Shared pixel for drawing solid rectangles and particles
+	game.pixel = ebiten.NewImage(1, 1)
+	game.pixel.Fill(color.White)
+
+	ebiten.SetWindowSize(game.settings.Window.Width, game.settings.Window.Height)
+	ebiten.SetFullscreen(game.settings.Window.Fullscreen)
+	ebiten.SetWindowTitle("RPG Demo - Aseprite Loading")
+
 	// Initialize audio context
 	game.audioContext = audio.NewContext(44100)
 
@@ -224,10 +551,7 @@ func main() {
 		log.Fatalf("Failed to create music player: %v", err)
 	}
 
-	// Set volume to low level (30% of maximum)
-	game.musicPlayer.SetVolume(0.3)
-
-	// Start playing the music
+	// Start playing the music (volume is applied once all players are loaded)
 	game.musicPlayer.Play()
 
 	// Load attack sound effect
@@ -249,9 +573,6 @@ func main() {
 		log.Fatalf("Failed to create attack sound player: %v", err)
 	}
 
-	// Set volume for attack sound (slightly higher than background music)
-	game.attackPlayer.SetVolume(0.5)
-
 	// Load orc hit sound effect
 	orcHitFile, err := os.Open("assets/orc_hit.mp3")
 	if err != nil {
@@ -271,9 +592,6 @@ func main() {
 		log.Fatalf("Failed to create orc hit sound player: %v", err)
 	}
 
-	// Set volume for orc hit sound
-	game.orcHitPlayer.SetVolume(0.4)
-
 	// Load orc die sound effect
 	orcDieFile, err := os.Open("assets/orc_die.mp3")
 	if err != nil {
@@ -293,15 +611,42 @@ func main() {
 		log.Fatalf("Failed to create orc die sound player: %v", err)
 	}
 
-	// Set volume for orc die sound
-	game.orcDiePlayer.SetVolume(0.4)
+	// Set volumes for all players from settings
+	game.applyAudioSettings()
+
+	// Load balance values
+	game.tuning, err = LoadTuning(tuningPath)
+	if err != nil {
+		log.Fatalf("Failed to load tuning: %v", err)
+	}
+
+	// Load particle presets; their bursts are purely visual, so they get their own random source
+	particleConfig, err := LoadParticleConfig(particlesPath)
+	if err != nil {
+		log.Fatalf("Failed to load particles: %v", err)
+	}
+	game.particles, err = NewParticleSystem(particleConfig, game.seed)
+	if err != nil {
+		log.Fatalf("Failed to load particle sprites: %v", err)
+	}
 
-	// Load the background image
-	backgroundImg, err := loadImageFromFile("assets/background.png")
+	// Load the fonts and lay out the HUD
+	fontConfig, err := LoadFontConfig(fontsPath)
 	if err != nil {
-		log.Fatalf("Failed to load background.png: %v", err)
+		log.Fatalf("Failed to load fonts: %v", err)
 	}
-	game.backgroundImage = backgroundImg
+	game.fonts, err = LoadFonts(fontConfig)
+	if err != nil {
+		log.Fatalf("Failed to load fonts: %v", err)
+	}
+	game.hud = NewHUD(game.fonts)
+
+	// React to combat with sound, a guard spark, particles and combat text
+	game.combatText = NewCombatText(game.tuning, game.fonts)
+	game.events.Listen(game.playGuardSound)
+	game.events.Listen(game.showGuardSpark)
+	game.events.Listen(game.particles.OnCombatEvent)
+	game.events.Listen(game.combatText.OnCombatEvent)
 
 	// Load the Soldier Aseprite file
 	aseFile, err := aseprite.LoadFile("assets/Soldier.aseprite")
@@ -319,15 +664,16 @@ func main() {
 	// Convert to Ebiten image
 	game.soldierSprite = ebiten.NewImageFromImage(frameImg)
 
-	// Initialize animation state for "Idle", "Walk", "Attack01", "Hurt", and "Death" tags
-	var idleTag, walkTag, attackTag, hurtTag, deathTag *aseprite.Tag
+	// Initialize animation state for "Idle", "Walk", "Hurt", and "Death" tags.
+	// Attack tags are looked up by name from the combo in the tuning file.
+	var idleTag, walkTag, hurtTag, deathTag *aseprite.Tag
+	game.playerTags = map[string][2]int{}
 	for _, tag := range aseFile.Tags {
+		game.playerTags[tag.Name] = [2]int{int(tag.FromFrame), int(tag.ToFrame)}
 		if tag.Name == "Idle" {
 			idleTag = tag
 		} else if tag.Name == "Walk" {
 			walkTag = tag
-		} else if tag.Name == "Attack02" {
-			attackTag = tag
 		} else if tag.Name == "Hurt" {
 			hurtTag = tag
 		} else if tag.Name == "Death" {
@@ -355,16 +701,22 @@ func main() {
 		game.walkFrameEnd = 13
 	}
 
-	if attackTag != nil {
-		game.attackFrameStart = int(attackTag.FromFrame)
-		game.attackFrameEnd = int(attackTag.ToFrame)
-		log.Printf("Found Attack01 animation: frames %d-%d", game.attackFrameStart, game.attackFrameEnd)
-	} else {
-		log.Printf("Warning: Attack01 animation tag not found")
-		game.attackFrameStart = 14
-		game.attackFrameEnd = 19
+	// The roll and block poses named in the tuning file must be in the sprite
+	if err := game.tuning.CheckTags(game.hasPlayerTag); err != nil {
+		log.Fatalf("Invalid tuning file: %v", err)
 	}
 
+	// Load the weapons, whose combos pick their animations by tag and their look by layer
+	game.weapons, err = LoadWeaponConfig(weaponsPath)
+	if err != nil {
+		log.Fatalf("Failed to load weapons: %v", err)
+	}
+	if err := game.weapons.CheckSprite(game.hasPlayerTag, game.hasPlayerLayer); err != nil {
+		log.Fatalf("Invalid weapon file: %v", err)
+	}
+	game.weaponName = game.weapons.Default
+	game.preloadWeaponSounds()
+
 	if hurtTag != nil {
 		game.hurtFrameStart = int(hurtTag.FromFrame)
 		game.hurtFrameEnd = int(hurtTag.ToFrame)
@@ -387,32 +739,79 @@ func main() {
 
 	// Initialize movement and animation state
 	game.currentFrame = game.idleFrameStart
-	game.frameDuration = 0.1 // 100ms = 0.1 seconds
 	game.frameTimer = 0
 	game.positionX = 0
+	game.positionY = game.tuning.Arena.ClampDepth(float64(screenHeight) * 0.2) // The original single lane
 	game.isWalking = false
 	game.facingLeft = false
-	game.walkSpeed = 5.0
 	game.isAttacking = false
 	game.playerState = PlayerStateAlive
-	game.playerHealth = 100.0 // Initialize player health to 100%
+	game.playerHealth = game.tuning.Player.MaxHealth // Start at full health
+	game.stamina = game.tuning.Player.Stamina.Max
 	game.deathTimer = 0
 	game.flashTimer = 0
 	game.flashVisible = true
 	game.flashCount = 0
 	game.orcsKilled = 0
+	game.stats = newPlayerStats()
+	game.playerLevel = 1
+	game.upgradeStacks = map[string]int{}
+
+	// Load the level and start the camera on the player
+	levels, err := LoadLevels(levelsPath)
+	if err != nil {
+		log.Fatalf("Failed to load levels: %v", err)
+	}
+	game.level, err = levels.Find(*levelName)
+	if err != nil {
+		log.Fatalf("Failed to pick level: %v", err)
+	}
+	game.camera = NewCamera(game.tuning, game.level, game.positionX, 0)
+	game.background, err = LoadBackground(game.level.Background)
+	if err != nil {
+		log.Fatalf("Failed to load the background of %s: %v", game.level.Name, err)
+	}
+
+	// Hit-stop, shake and flashes react to combat once there's a camera to shake
+	game.juice = NewJuice(game.tuning, game.settings, game.camera)
+	game.hitFlashShader, err = ebiten.NewShader(flashShaderSource)
+	if err != nil {
+		log.Fatalf("Failed to compile the hit flash shader: %v", err)
+	}
+	game.events.Listen(game.applyJuice)
+	log.Printf("Playing level %s", game.level.Name)
 
-	// Initialize spawn system
+	// Load enemy archetypes
+	enemyDefinitions, err := LoadEnemyDefinitions(enemiesPath)
+	if err != nil {
+		log.Fatalf("Failed to load enemies: %v", err)
+	}
+	game.enemies = NewEnemyFactory(enemyDefinitions, game.tuning)
+	game.preloadBossMusic(enemyDefinitions)
+
+	// Initialize spawn system from the wave data file
 	game.orcs = make([]*Orc, 0)
-	game.spawnTimer = 0
-	game.spawnInterval = 9.0 // Start with 9 seconds between spawns (tripled)
+	waveConfig, err := LoadWaveConfig(wavesPath, game.enemies.Has)
+	if err != nil {
+		log.Fatalf("Failed to load waves: %v", err)
+	}
+	game.director = NewSpawnDirector(waveConfig, game.rng)
+
+	// Load pickups and which enemies drop them
+	game.pickupConfig, err = LoadPickupConfig(pickupsPath, game.enemies.Has, game.weapons.Has)
+	if err != nil {
+		log.Fatalf("Failed to load pickups: %v", err)
+	}
+	game.pickupIcons, err = LoadPickupIcons(game.pickupConfig, game.weapons)
+	if err != nil {
+		log.Fatalf("Failed to load pickup icons: %v", err)
+	}
 
-	// Create the first orc enemy
-	orc, err := NewOrc(300, float64(screenHeight)*0.2) // Position orc to the right of center
+	// Load the level curve and the upgrades offered on leveling up
+	game.upgradeConfig, err = LoadUpgradeConfig(upgradesPath)
 	if err != nil {
-		log.Fatalf("Failed to create orc: %v", err)
+		log.Fatalf("Failed to load upgrades: %v", err)
 	}
-	game.orcs = append(game.orcs, orc)
 
 	log.Printf("Loaded Aseprite file: %dx%d, %d frames, %d bpp",
 		aseFile.Header.Width, aseFile.Header.Height,
@@ -421,4 +820,22 @@ func main() {
 	if err := ebiten.RunGame(game); err != nil {
 		log.Fatal(err)
 	}
+
+	if game.recorder != nil {
+		game.recorder.FinalHash = game.StateHash()
+		if err := game.recorder.Save(*recordPath); err != nil {
+			log.Fatalf("Failed to save replay: %v", err)
+		}
+		log.Printf("Saved replay to %s (%d ticks, final state %016x)", *recordPath, len(game.recorder.Ticks), game.recorder.FinalHash)
+	}
+
+	if game.replay != nil {
+		if game.replayTick != len(game.replay.Ticks) {
+			log.Fatalf("Replay desync: run ended after %d of %d ticks", game.replayTick, len(game.replay.Ticks))
+		}
+		if hash := game.StateHash(); hash != game.replay.FinalHash {
+			log.Fatalf("Replay desync: final state %016x, expected %016x", hash, game.replay.FinalHash)
+		}
+		log.Printf("Replay verified: final state %016x matches", game.replay.FinalHash)
+	}
 }
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// menuItem is a single line in the settings menu
+type menuItem struct {
+	label    func() string
+	activate func()        // Called when the item is confirmed (Enter / A)
+	adjust   func(dir int) // Called with -1 or +1 for left/right, may be nil
+}
+
+// SettingsMenu is the pause menu where settings can be changed and actions rebound.
+// Its navigation keys are fixed so a bad binding can never lock the player out.
+type SettingsMenu struct {
+	game     *Game
+	items    []menuItem
+	selected int
+	open     bool
+	dirty    bool // Whether settings changed since the menu was opened
+
+	// Rebinding state: when capturing, the next input pressed is bound to rebindAction
+	capturing    bool
+	rebindAction Action
+
+	overlay    *ebiten.Image
+	gamepadIDs []ebiten.GamepadID
+}
+
+// volumeStep is how much a volume setting changes per left/right press
+const volumeStep = 0.1
+
+// NewSettingsMenu creates the pause menu for a game
+func NewSettingsMenu(g *Game) *SettingsMenu {
+	m := &SettingsMenu{game: g}
+
+	overlay := ebiten.NewImage(1, 1)
+	overlay.Fill(color.RGBA{0, 0, 0, 180})
+	m.overlay = overlay
+
+	m.items = []menuItem{
+		{
+			label:    func() string { return "Resume" },
+			activate: m.Close,
+		},
+		m.volumeItem("Master Volume", &g.settings.Audio.MasterVolume),
+		m.volumeItem("Music Volume", &g.settings.Audio.MusicVolume),
+		m.volumeItem("Effects Volume", &g.settings.Audio.EffectsVolume),
+		{
+			label: func() string {
+				if g.settings.Input.AttackMode == AttackModeHold {
+					return "Attack Mode: Hold to attack"
+				}
+				return "Attack Mode: Press to attack"
+			},
+			activate: m.toggleAttackMode,
+			adjust:   func(int) { m.toggleAttackMode() },
+		},
+		m.toggleItem("Reduce Flashing", &g.settings.Accessibility.ReduceFlashing, nil),
+		m.toggleItem("Hit-Stop", &g.settings.Accessibility.HitStop, nil),
+		m.toggleItem("Screen Shake", &g.settings.Accessibility.ScreenShake, nil),
+		m.toggleItem("Hit Flash", &g.settings.Accessibility.HitFlash, nil),
+		m.toggleItem("Slow Motion", &g.settings.Accessibility.SlowMotion, nil),
+		m.toggleItem("Fullscreen", &g.settings.Window.Fullscreen, func() {
+			ebiten.SetFullscreen(g.settings.Window.Fullscreen)
+		}),
+	}
+
+	for a := Action(0); a < actionCount; a++ {
+		action := a
+		m.items = append(m.items, menuItem{
+			label: func() string {
+				if m.capturing && m.rebindAction == action {
+					return fmt.Sprintf("%s: press a key or button... (Esc to cancel)", actionLabels[action])
+				}
+				return fmt.Sprintf("%s: %s", actionLabels[action], bindingsLabel(g.settings.Input.Bindings[action]))
+			},
+			activate: func() {
+				m.capturing = true
+				m.rebindAction = action
+			},
+		})
+	}
+
+	m.items = append(m.items,
+		menuItem{
+			label: func() string { return "Restore Default Bindings" },
+			activate: func() {
+				g.settings.Input.Bindings = defaultBindings()
+				m.dirty = true
+			},
+		},
+		menuItem{
+			label: func() string { return "Quit Game" },
+			activate: func() {
+				m.save()
+				g.quit = true
+			},
+		},
+	)
+
+	return m
+}
+
+// volumeItem creates a menu item that adjusts a volume setting
+func (m *SettingsMenu) volumeItem(name string, value *float64) menuItem {
+	return menuItem{
+		label: func() string { return fmt.Sprintf("%s: < %3.0f%% >", name, *value*100) },
+		adjust: func(dir int) {
+			// Round to whole steps so repeated presses don't accumulate float error
+			*value = math.Round((*value+float64(dir)*volumeStep)*10) / 10
+			*value = math.Max(0, math.Min(1, *value))
+			m.game.applyAudioSettings()
+			m.dirty = true
+		},
+	}
+}
+
+// toggleItem creates a menu item that flips a boolean setting
+func (m *SettingsMenu) toggleItem(name string, value *bool, onChange func()) menuItem {
+	toggle := func() {
+		*value = !*value
+		if onChange != nil {
+			onChange()
+		}
+		m.dirty = true
+	}
+	return menuItem{
+		label: func() string {
+			if *value {
+				return name + ": On"
+			}
+			return name + ": Off"
+		},
+		activate: toggle,
+		adjust:   func(int) { toggle() },
+	}
+}
+
+// toggleAttackMode switches between press-to-attack and hold-to-attack
+func (m *SettingsMenu) toggleAttackMode() {
+	if m.game.settings.Input.AttackMode == AttackModeHold {
+		m.game.settings.Input.AttackMode = AttackModePress
+	} else {
+		m.game.settings.Input.AttackMode = AttackModeHold
+	}
+	m.dirty = true
+}
+
+// bindingsLabel joins binding labels for display
+func bindingsLabel(bindings []Binding) string {
+	label := ""
+	for i, b := range bindings {
+		if i > 0 {
+			label += ", "
+		}
+		label += b.Label()
+	}
+	return label
+}
+
+// IsOpen reports whether the menu is showing
+func (m *SettingsMenu) IsOpen() bool {
+	return m.open
+}
+
+// Open shows the menu with the first item selected
+func (m *SettingsMenu) Open() {
+	m.open = true
+	m.selected = 0
+	m.capturing = false
+	m.dirty = false
+}
+
+// Close hides the menu and saves any changed settings
+func (m *SettingsMenu) Close() {
+	m.open = false
+	m.capturing = false
+	m.save()
+
+	// Don't let the keys used in the menu leak into gameplay
+	m.game.resetInput = true
+}
+
+// save writes settings to disk if anything changed
+func (m *SettingsMenu) save() {
+	if !m.dirty || m.game.settingsPath == "" {
+		return
+	}
+	if err := m.game.settings.Save(m.game.settingsPath); err != nil {
+		log.Printf("Failed to save settings: %v", err)
+	}
+	m.dirty = false
+}
+
+// Update handles menu navigation for one tick.
+// pausePressed reports whether the (rebindable) pause action was just pressed.
+func (m *SettingsMenu) Update(pausePressed bool) {
+	m.gamepadIDs = ebiten.AppendGamepadIDs(m.gamepadIDs[:0])
+
+	if m.capturing {
+		m.updateCapture()
+		return
+	}
+
+	switch {
+	case m.pressedKey(ebiten.KeyEscape) || m.pressedButton(ebiten.StandardGamepadButtonCenterRight) || pausePressed:
+		m.Close()
+	case m.pressedKey(ebiten.KeyArrowUp) || m.pressedKey(ebiten.KeyW) || m.pressedButton(ebiten.StandardGamepadButtonLeftTop):
+		m.selected = (m.selected + len(m.items) - 1) % len(m.items)
+	case m.pressedKey(ebiten.KeyArrowDown) || m.pressedKey(ebiten.KeyS) || m.pressedButton(ebiten.StandardGamepadButtonLeftBottom):
+		m.selected = (m.selected + 1) % len(m.items)
+	case m.pressedKey(ebiten.KeyArrowLeft) || m.pressedKey(ebiten.KeyA) || m.pressedButton(ebiten.StandardGamepadButtonLeftLeft):
+		if item := m.items[m.selected]; item.adjust != nil {
+			item.adjust(-1)
+		}
+	case m.pressedKey(ebiten.KeyArrowRight) || m.pressedKey(ebiten.KeyD) || m.pressedButton(ebiten.StandardGamepadButtonLeftRight):
+		if item := m.items[m.selected]; item.adjust != nil {
+			item.adjust(1)
+		}
+	case m.pressedKey(ebiten.KeyEnter) || m.pressedKey(ebiten.KeySpace) || m.pressedButton(ebiten.StandardGamepadButtonRightBottom):
+		if item := m.items[m.selected]; item.activate != nil {
+			item.activate()
+		}
+	}
+}
+
+// updateCapture waits for the next key, button or stick movement and binds it
+func (m *SettingsMenu) updateCapture() {
+	if m.pressedKey(ebiten.KeyEscape) {
+		m.capturing = false
+		return
+	}
+
+	if keys := inpututil.AppendJustPressedKeys(nil); len(keys) > 0 {
+		m.rebind(KeyBinding(keys[0]))
+		return
+	}
+
+	for _, id := range m.gamepadIDs {
+		if !ebiten.IsStandardGamepadLayoutAvailable(id) {
+			continue
+		}
+		if buttons := inpututil.AppendJustPressedStandardGamepadButtons(id, nil); len(buttons) > 0 {
+			m.rebind(ButtonBinding(buttons[0]))
+			return
+		}
+		for axis := range gamepadAxisNames {
+			value := ebiten.StandardGamepadAxisValue(id, axis)
+			if math.Abs(value) >= 0.75 {
+				m.rebind(AxisBinding(axis, value > 0))
+				return
+			}
+		}
+	}
+}
+
+// rebind replaces the bindings of the same device type for the action being rebound.
+// The input is removed from every other action so one input never triggers two actions.
+func (m *SettingsMenu) rebind(binding Binding) {
+	bindings := m.game.settings.Input.Bindings
+	for action, list := range bindings {
+		kept := list[:0:0]
+		for _, b := range list {
+			if b == binding {
+				continue
+			}
+			if action == m.rebindAction && b.isGamepad() == binding.isGamepad() {
+				continue
+			}
+			kept = append(kept, b)
+		}
+		bindings[action] = kept
+	}
+	bindings[m.rebindAction] = append(bindings[m.rebindAction], binding)
+
+	// Actions left without any binding fall back to their defaults
+	m.game.settings.Validate()
+
+	m.capturing = false
+	m.dirty = true
+}
+
+// pressedKey reports whether a fixed menu key was just pressed
+func (m *SettingsMenu) pressedKey(key ebiten.Key) bool {
+	return inpututil.IsKeyJustPressed(key)
+}
+
+// pressedButton reports whether a fixed menu button was just pressed on any gamepad
+func (m *SettingsMenu) pressedButton(button ebiten.StandardGamepadButton) bool {
+	for _, id := range m.gamepadIDs {
+		if ebiten.IsStandardGamepadLayoutAvailable(id) && inpututil.IsStandardGamepadButtonJustPressed(id, button) {
+			return true
+		}
+	}
+	return false
+}
+
+// Draw renders the menu on top of the paused game
+func (m *SettingsMenu) Draw(screen *ebiten.Image) {
+	if !m.open {
+		return
+	}
+
+	// Darken the game behind the menu
+	opts := &ebiten.DrawImageOptions{}
+	opts.GeoM.Scale(screenWidth, screenHeight)
+	screen.DrawImage(m.overlay, opts)
+
+	font := m.game.fonts.HUD
+	_, height := font.Measure("0")
+	lineHeight := height + 6
+	x := float64(screenWidth/2 - 200)
+	y := (screenHeight - float64(len(m.items))*lineHeight) / 2
+
+	drawLine := func(label string, y float64, c color.RGBA) {
+		opts := &ebiten.DrawImageOptions{}
+		opts.GeoM.Translate(x, y)
+		opts.ColorScale.ScaleWithColor(c)
+		font.Draw(screen, label, opts)
+	}
+
+	drawLine("PAUSED - Settings", y-2*lineHeight, color.RGBA{255, 255, 255, 255})
+
+	for i, item := range m.items {
+		itemColor := color.RGBA{180, 180, 180, 255}
+		label := "  " + item.label()
+		if i == m.selected {
+			itemColor = color.RGBA{255, 220, 80, 255}
+			label = "> " + item.label()
+		}
+		drawLine(label, y+float64(i)*lineHeight, itemColor)
+	}
+}
@@ -1,16 +1,22 @@
 package main
 
 import (
-	"rpg_demo/aseprite"
+	"fmt"
+	"math"
 
 	"github.com/hajimehoshi/ebiten/v2"
 )
 
-// Orc represents an enemy orc character
+// Orc represents an enemy character. Orcs, goblins and brutes are all Orcs;
+// what kind of enemy it is comes from its EnemyDefinition.
 type Orc struct {
 	// Sprite and animation data
-	sprite       *ebiten.Image
-	asepriteFile *aseprite.File
+	sprite *ebiten.Image
+	sheet  *SpriteSheet
+
+	// Archetype data shared by every enemy of this type (may be reloaded at runtime)
+	def       *EnemyDefinition
+	enemyType string // Name of the definition in enemiesPath, e.g. "goblin"
 
 	// Position and movement
 	positionX  float64
@@ -18,9 +24,8 @@ type Orc struct {
 	facingLeft bool
 
 	// Animation state
-	currentFrame  int
-	frameTimer    float64
-	frameDuration float64 // in seconds
+	currentFrame int
+	frameTimer   float64
 
 	// Animation frame ranges
 	idleFrameStart     int
@@ -40,16 +45,34 @@ type Orc struct {
 	state OrcState
 
 	// AI and movement
-	walkSpeed   float64
-	patrolLeft  float64 // Left boundary of patrol area
-	patrolRight float64 // Right boundary of patrol area
-	movingRight bool    // Direction of movement
+	walkSpeed float64
+	brain     Behavior // Behavior tree built from the definition's behavior
+	ai        AIAgent  // What the brain sees of this orc, plus its memory
 
 	// Combat
 	health     int
 	maxHealth  int
 	hurtTimer  float64 // Timer for hurt state duration
 	knockbackX float64 // Knockback velocity
+	shieldHits int     // Frontal hits the shield can still block
+	blockTimer float64 // Time left during which further hits are ignored after a block
+	hitFlash   float64 // Time left of the white flash after taking damage
+
+	// Boss fights (only used when def.Boss is set)
+	bossPhase      int      // Index into def.Boss.Phases
+	specialFrames  [][2]int // Frame range of each phase's special attack tag
+	specialTimer   float64  // Time left until the next special attack
+	telegraphTimer float64  // Warning time left before the special attack swings
+
+	// Attacking
+	attackCooldown float64 // Recovery time left before the orc can move or attack again
+	attackCount    int     // Swings started so far, used to pick heavy attacks
+	attackHasHit   bool    // Whether the current swing already hit the player
+
+	lastPlayerSwing int // Last player swing that hit this orc, so each swing hits once
+
+	// Balance values shared with the rest of the game (may be reloaded at runtime)
+	tuning *Tuning
 
 	// Death sequence
 	deathTimer   float64 // Timer for death sequence
@@ -69,86 +92,113 @@ const (
 	OrcStateAttack02
 	OrcStateHurt
 	OrcStateDeath
+	OrcStateTelegraph   // Boss winding up a special attack
+	OrcStateSpecial     // Boss performing a special attack
+	OrcStateKnockedDown // Knocked off its feet by a finisher
+	OrcStateStunned     // Reeling from a parried attack
 )
 
-// NewOrc creates a new Orc instance
-func NewOrc(x, y float64) (*Orc, error) {
-	// Load the Orc Aseprite file
-	aseFile, err := aseprite.LoadFile("assets/Orc.aseprite")
-	if err != nil {
-		return nil, err
-	}
-
+// NewOrc creates an enemy from its definition. Use EnemyFactory.Spawn, which
+// also takes care of loading and sharing the sprite sheet.
+func NewOrc(def *EnemyDefinition, sheet *SpriteSheet, x, y float64, tuning *Tuning) (*Orc, error) {
 	// Get the first frame as an image
-	frameImg, err := aseFile.GetFrameImage(0)
+	frameImg, err := sheet.Frame(0)
 	if err != nil {
 		return nil, err
 	}
 
 	orc := &Orc{
-		sprite:        ebiten.NewImageFromImage(frameImg),
-		asepriteFile:  aseFile,
-		positionX:     x,
-		positionY:     y,
-		facingLeft:    false,
-		currentFrame:  0,
-		frameDuration: 0.1, // 100ms = 0.1 seconds
-		frameTimer:    0,
-		state:         OrcStateWalk, // Start walking
-		walkSpeed:     2.0,          // Slower than player
-		patrolLeft:    x - 150,      // Patrol 150 pixels left of starting position
-		patrolRight:   x + 150,      // Patrol 150 pixels right of starting position
-		movingRight:   true,         // Start moving right
-		health:        3,            // Takes 3 hits to defeat
-		maxHealth:     3,
-		hurtTimer:     0,
-		knockbackX:    0,
-		deathTimer:    0,
-		flashTimer:    0,
-		flashVisible:  true,
-		flashCount:    0,
-		shouldRemove:  false,
+		sprite:       frameImg,
+		sheet:        sheet,
+		def:          def,
+		positionX:    x,
+		positionY:    y,
+		facingLeft:   false,
+		currentFrame: 0,
+		frameTimer:   0,
+		state:        OrcStateWalk,  // Start walking
+		walkSpeed:    def.WalkSpeed, // Scaled per wave by the spawner
+		brain:        enemyBehaviors[def.Behavior](def),
+		health:       def.Health, // Number of hits to defeat
+		maxHealth:    def.Health,
+		shieldHits:   def.ShieldHits, // Frontal hits the shield can still block
+		hurtTimer:    0,
+		knockbackX:   0,
+		tuning:       tuning,
+		deathTimer:   0,
+		flashTimer:   0,
+		flashVisible: true,
+		flashCount:   0,
+		shouldRemove: false,
 	}
 
 	// Initialize animation frame ranges from tags
-	orc.initializeAnimationRanges()
+	if err := orc.initializeAnimationRanges(); err != nil {
+		return nil, err
+	}
 
 	return orc, nil
 }
 
 // initializeAnimationRanges sets up the frame ranges for each animation
-func (o *Orc) initializeAnimationRanges() {
-	for _, tag := range o.asepriteFile.Tags {
-		switch tag.Name {
-		case "idle":
-			o.idleFrameStart = int(tag.FromFrame)
-			o.idleFrameEnd = int(tag.ToFrame)
-		case "walk":
-			o.walkFrameStart = int(tag.FromFrame)
-			o.walkFrameEnd = int(tag.ToFrame)
-		case "attack01":
-			o.attack01FrameStart = int(tag.FromFrame)
-			o.attack01FrameEnd = int(tag.ToFrame)
-		case "attack02":
-			o.attack02FrameStart = int(tag.FromFrame)
-			o.attack02FrameEnd = int(tag.ToFrame)
-		case "hurt":
-			o.hurtFrameStart = int(tag.FromFrame)
-			o.hurtFrameEnd = int(tag.ToFrame)
-		case "Death":
-			o.deathFrameStart = int(tag.FromFrame)
-			o.deathFrameEnd = int(tag.ToFrame)
+// using the tag names from the enemy definition
+func (o *Orc) initializeAnimationRanges() error {
+	ranges := []struct {
+		tag        string
+		start, end *int
+	}{
+		{o.def.Tags.Idle, &o.idleFrameStart, &o.idleFrameEnd},
+		{o.def.Tags.Walk, &o.walkFrameStart, &o.walkFrameEnd},
+		{o.def.Tags.Attack01, &o.attack01FrameStart, &o.attack01FrameEnd},
+		{o.def.Tags.Attack02, &o.attack02FrameStart, &o.attack02FrameEnd},
+		{o.def.Tags.Hurt, &o.hurtFrameStart, &o.hurtFrameEnd},
+		{o.def.Tags.Death, &o.deathFrameStart, &o.deathFrameEnd},
+	}
+	for _, r := range ranges {
+		from, to, ok := o.sheet.TagRange(r.tag)
+		if !ok {
+			return fmt.Errorf("%s: animation tag %q not found in %s", o.def.Name, r.tag, o.def.Sprite)
+		}
+		*r.start, *r.end = from, to
+	}
+
+	// Bosses also need the tag of every phase's special attack
+	if o.def.Boss != nil {
+		o.specialFrames = make([][2]int, len(o.def.Boss.Phases))
+		for i, phase := range o.def.Boss.Phases {
+			from, to, ok := o.sheet.TagRange(phase.Special.Tag)
+			if !ok {
+				return fmt.Errorf("%s: animation tag %q not found in %s", o.def.Name, phase.Special.Tag, o.def.Sprite)
+			}
+			o.specialFrames[i] = [2]int{from, to}
 		}
+		o.specialTimer = o.def.Boss.Phases[0].Special.Every
 	}
 
 	// Start with walk animation since we begin walking
 	o.currentFrame = o.walkFrameStart
+	return nil
+}
+
+// patrolLimit is how far from the center of the view a patrol area can be centered
+const patrolLimit = screenWidth/2 - 200
+
+// SetPatrolArea sets the orc to patrol 150 pixels either side of its spawn point,
+// pulled in so the patrol area is in view (centered on viewX) even for enemies
+// entering from the edges
+func (o *Orc) SetPatrolArea(viewX float64) {
+	patrolCenter := viewX + math.Max(-patrolLimit, math.Min(patrolLimit, o.positionX-viewX))
+	o.ai.PatrolLeft = patrolCenter - 150
+	o.ai.PatrolRight = patrolCenter + 150
+	o.ai.MovingRight = o.positionX < patrolCenter // Walk into view first
 }
 
-// Update handles the orc's logic updates
-func (o *Orc) Update(playerX float64) error {
-	// Handle hurt state timing
-	if o.state == OrcStateHurt {
+// Update handles the orc's logic updates. ctx describes the player and the
+// crowd of living enemies for the orc's brain; ctx.Self is filled in here.
+func (o *Orc) Update(ctx *AIContext) error {
+
+	// Handle hurt, knockdown and stun timing
+	if o.state == OrcStateHurt || o.state == OrcStateKnockedDown || o.state == OrcStateStunned {
 		o.hurtTimer -= 1.0 / 60.0 // Decrease timer
 		if o.hurtTimer <= 0 {
 			// Hurt state finished, return to walking
@@ -156,6 +206,39 @@ func (o *Orc) Update(playerX float64) error {
 		}
 	}
 
+	if o.blockTimer > 0 {
+		o.blockTimer -= 1.0 / 60.0
+	}
+	if o.hitFlash > 0 {
+		o.hitFlash -= 1.0 / 60.0
+	}
+
+	// Bosses count down to their next special attack and telegraph it before swinging
+	if o.def.Boss != nil && o.state != OrcStateDeath {
+		if o.state != OrcStateTelegraph && o.state != OrcStateSpecial {
+			// Phases only change between specials so a swing never switches animation halfway
+			o.updateBossPhase()
+			if o.specialTimer > 0 {
+				o.specialTimer -= 1.0 / 60.0
+			}
+		}
+		if o.state == OrcStateTelegraph {
+			o.telegraphTimer -= 1.0 / 60.0
+			if o.telegraphTimer <= 0 {
+				o.attackHasHit = false
+				o.setState(OrcStateSpecial)
+			}
+		}
+	}
+
+	// Recover after an attack before moving again
+	if o.attackCooldown > 0 {
+		o.attackCooldown -= 1.0 / 60.0
+	}
+	if o.state == OrcStateIdle && o.attackCooldown <= 0 {
+		o.setState(OrcStateWalk)
+	}
+
 	// Handle death sequence
 	if o.state == OrcStateDeath {
 		o.deathTimer -= 1.0 / 60.0 // Decrease timer
@@ -165,14 +248,14 @@ func (o *Orc) Update(playerX float64) error {
 			if o.flashTimer <= 0 {
 				// Toggle visibility
 				o.flashVisible = !o.flashVisible
-				o.flashTimer = 0.1 // Flash every 0.1 seconds
+				o.flashTimer = o.tuning.Enemy.FlashInterval
 
 				if !o.flashVisible {
 					o.flashCount++
 				}
 
-				// After 6 flashes (3 on/off cycles), mark for removal
-				if o.flashCount >= 6 {
+				// After enough flashes, mark for removal
+				if o.flashCount >= o.tuning.Enemy.FlashCount {
 					o.shouldRemove = true
 				}
 			}
@@ -183,33 +266,23 @@ func (o *Orc) Update(playerX float64) error {
 	if o.knockbackX != 0 {
 		o.positionX += o.knockbackX
 		// Apply friction to knockback
-		o.knockbackX *= 0.9
+		o.knockbackX *= o.tuning.Enemy.KnockbackFriction
 		// Stop knockback when it's very small
 		if o.knockbackX > -1 && o.knockbackX < 1 {
 			o.knockbackX = 0
 		}
 	}
 
-	// Handle player-chasing AI (only when walking)
+	// Let the brain decide what to do (only when walking)
 	if o.state == OrcStateWalk {
-		// Move towards the player
-		if playerX > o.positionX {
-			// Player is to the right, move right
-			o.positionX += o.walkSpeed
-			o.facingLeft = false
-		} else if playerX < o.positionX {
-			// Player is to the left, move left
-			o.positionX -= o.walkSpeed
-			o.facingLeft = true
-		}
-		// If playerX == o.positionX, don't move horizontally
+		o.think(ctx)
 	}
 
 	// Update animation timer
 	o.frameTimer += 1.0 / 60.0 // Assuming 60 FPS
 
 	// Check if it's time to advance to the next frame
-	if o.frameTimer >= o.frameDuration {
+	if o.frameTimer >= o.frameDuration() {
 		o.frameTimer = 0
 		o.currentFrame++
 
@@ -225,13 +298,13 @@ func (o *Orc) Update(playerX float64) error {
 			}
 		case OrcStateAttack01:
 			if o.currentFrame > o.attack01FrameEnd {
-				o.setState(OrcStateIdle)
+				o.finishAttack()
 			}
 		case OrcStateAttack02:
 			if o.currentFrame > o.attack02FrameEnd {
-				o.setState(OrcStateIdle)
+				o.finishAttack()
 			}
-		case OrcStateHurt:
+		case OrcStateHurt, OrcStateStunned:
 			if o.currentFrame > o.hurtFrameEnd {
 				// Don't change state here - let the timer handle it
 				o.currentFrame = o.hurtFrameStart
@@ -241,20 +314,152 @@ func (o *Orc) Update(playerX float64) error {
 				// Stay on the last frame of death animation
 				o.currentFrame = o.deathFrameEnd
 			}
+		case OrcStateKnockedDown:
+			// Fall over using the death animation and lie on its last frame
+			if o.currentFrame > o.deathFrameEnd {
+				o.currentFrame = o.deathFrameEnd
+			}
+		case OrcStateTelegraph:
+			// Hold the first frame of the special attack as a wind-up pose
+			o.currentFrame = o.specialFrames[o.bossPhase][0]
+		case OrcStateSpecial:
+			if o.currentFrame > o.specialFrames[o.bossPhase][1] {
+				o.specialTimer = o.def.Boss.Phases[o.bossPhase].Special.Every
+				o.finishAttack()
+			}
 		}
 
 		// Update the sprite image to the current frame
-		if o.asepriteFile != nil {
-			frameImg, err := o.asepriteFile.GetFrameImage(o.currentFrame)
-			if err == nil {
-				o.sprite = ebiten.NewImageFromImage(frameImg)
-			}
+		if frameImg, err := o.sheet.Frame(o.currentFrame); err == nil {
+			o.sprite = frameImg
 		}
 	}
 
 	return nil
 }
 
+// think ticks the orc's brain and carries out its intent
+func (o *Orc) think(ctx *AIContext) {
+	// Bosses use their special attack whenever it's ready and the player is in its reach
+	if special := o.currentSpecial(); special != nil && o.specialTimer <= 0 &&
+		math.Abs(ctx.PlayerX-o.positionX) <= special.Reach*o.def.Scale &&
+		withinHitDepth(o.tuning, o.positionY, ctx.PlayerY) {
+		o.facingLeft = ctx.PlayerX < o.positionX
+		o.telegraphTimer = special.Telegraph
+		o.setState(OrcStateTelegraph)
+		return
+	}
+
+	reach := o.nextAttack().Reach * o.def.Scale
+	o.ai.X = o.positionX
+	o.ai.Y = o.positionY
+	o.ai.Speed = o.walkSpeed
+	if o.def.Boss != nil {
+		o.ai.Speed *= o.def.Boss.Phases[o.bossPhase].SpeedMultiplier
+	}
+	o.ai.Reach = reach
+	o.ai.BodyWidth = o.def.BodySize * o.def.Scale
+	o.ai.FacingLeft = o.facingLeft
+	o.ai.Health = o.health
+	o.ai.MaxHealth = o.maxHealth
+	ctx.Self = &o.ai
+
+	intent := ctx.Tick(o.brain)
+
+	if intent.Attack && math.Abs(ctx.PlayerX-o.positionX) <= reach && withinHitDepth(o.tuning, o.positionY, ctx.PlayerY) {
+		// Close enough to swing: face the player and start winding up
+		o.facingLeft = ctx.PlayerX < o.positionX
+		o.startAttack()
+		return
+	}
+
+	o.positionX += intent.MoveX
+	o.positionY = o.tuning.Arena.ClampDepth(o.positionY + intent.MoveY)
+	switch {
+	case intent.Face < 0:
+		o.facingLeft = true
+	case intent.Face > 0:
+		o.facingLeft = false
+	case intent.MoveX != 0:
+		o.facingLeft = intent.MoveX < 0
+	}
+}
+
+// nextAttack returns the attack the orc will use on its next swing.
+// Every HeavyEvery-th swing is the heavy Attack02.
+func (o *Orc) nextAttack() *EnemyAttack {
+	if (o.attackCount+1)%o.def.HeavyEvery == 0 {
+		return &o.def.Attack02
+	}
+	return &o.def.Attack01
+}
+
+// startAttack begins the next swing; damage is only dealt on its active frames
+func (o *Orc) startAttack() {
+	o.attackCount++
+	o.attackHasHit = false
+	if o.attackCount%o.def.HeavyEvery == 0 {
+		o.setState(OrcStateAttack02)
+	} else {
+		o.setState(OrcStateAttack01)
+	}
+}
+
+// finishAttack ends the swing and starts the recovery cooldown
+func (o *Orc) finishAttack() {
+	o.attackCooldown = o.currentAttack().Cooldown
+	o.setState(OrcStateIdle)
+}
+
+// currentAttack returns the attack being performed, or nil if the orc isn't attacking
+func (o *Orc) currentAttack() *EnemyAttack {
+	switch o.state {
+	case OrcStateAttack01:
+		return &o.def.Attack01
+	case OrcStateAttack02:
+		return &o.def.Attack02
+	case OrcStateSpecial:
+		return &o.currentSpecial().EnemyAttack
+	}
+	return nil
+}
+
+// currentSpecial returns the special attack of the boss's current phase,
+// or nil if the orc isn't a boss
+func (o *Orc) currentSpecial() *BossSpecial {
+	if o.def.Boss == nil {
+		return nil
+	}
+	return &o.def.Boss.Phases[o.bossPhase].Special
+}
+
+// isAttackActive reports whether the current attack frame can deal damage
+func (o *Orc) isAttackActive() bool {
+	attack := o.currentAttack()
+	if attack == nil {
+		return false
+	}
+
+	tagStart := o.attack01FrameStart
+	switch o.state {
+	case OrcStateAttack02:
+		tagStart = o.attack02FrameStart
+	case OrcStateSpecial:
+		tagStart = o.specialFrames[o.bossPhase][0]
+	}
+	frame := o.currentFrame - tagStart
+	return frame >= attack.ActiveFrames[0] && frame <= attack.ActiveFrames[1]
+}
+
+// frameDuration returns the animation speed for the current state;
+// attacks have their own speed so heavy swings wind up more slowly
+func (o *Orc) frameDuration() float64 {
+	if attack := o.currentAttack(); attack != nil {
+		return attack.FrameDuration
+	}
+	return o.tuning.Enemy.FrameDuration
+}
+
 // setState changes the orc's state and resets animation
 func (o *Orc) setState(newState OrcState) {
 	if o.state == newState {
@@ -274,163 +479,275 @@ func (o *Orc) setState(newState OrcState) {
 		o.currentFrame = o.attack01FrameStart
 	case OrcStateAttack02:
 		o.currentFrame = o.attack02FrameStart
-	case OrcStateHurt:
+	case OrcStateHurt, OrcStateStunned:
 		o.currentFrame = o.hurtFrameStart
-	case OrcStateDeath:
+	case OrcStateDeath, OrcStateKnockedDown:
 		o.currentFrame = o.deathFrameStart
+	case OrcStateTelegraph, OrcStateSpecial:
+		o.currentFrame = o.specialFrames[o.bossPhase][0]
 	}
 }
 
-// Draw renders the orc to the screen
-func (o *Orc) Draw(screen *ebiten.Image) {
+// Draw renders the orc to the screen through the camera
+// If reduceFlashing is set, the orc stays visible during its death flashing.
+// flash is the hit flash shader, or nil if hit flashes are turned off.
+func (o *Orc) Draw(screen *ebiten.Image, camera *Camera, flash *ebiten.Shader, reduceFlashing bool) {
 	if o.sprite == nil {
 		return
 	}
 
 	// Don't draw if flashing and currently invisible
-	if o.state == OrcStateDeath && o.deathTimer <= 0 && !o.flashVisible {
+	if o.state == OrcStateDeath && o.deathTimer <= 0 && !o.flashVisible && !reduceFlashing {
 		return
 	}
 
 	opts := &ebiten.DrawImageOptions{}
 
-	// Scale the sprite 10x larger (same as player)
-	const scale = 10.0
-	opts.GeoM.Scale(scale, scale)
-
-	// Calculate sprite dimensions
-	spriteWidth := float64(o.sprite.Bounds().Dx()) * scale
-	spriteHeight := float64(o.sprite.Bounds().Dy()) * scale
+	// Apply the archetype's tint
+	opts.ColorScale.Scale(float32(o.def.Tint[0]), float32(o.def.Tint[1]), float32(o.def.Tint[2]), 1)
 
-	// Calculate final position
-	finalX := (float64(screenWidth)-spriteWidth)/2 + o.positionX
-	finalY := (float64(screenHeight)-spriteHeight)/2 + o.positionY
-
-	// If facing left, flip around the center of the sprite
-	if o.facingLeft {
-		// Translate to center, flip, then translate back
-		opts.GeoM.Translate(-spriteWidth/2, -spriteHeight/2)
-		opts.GeoM.Scale(-1, 1)
-		opts.GeoM.Translate(spriteWidth/2, spriteHeight/2)
+	// Glow red while telegraphing a special attack (steadily if flashing is reduced)
+	if o.state == OrcStateTelegraph {
+		glow := 0.5
+		if !reduceFlashing {
+			glow = 0.5 + 0.5*math.Sin(o.telegraphTimer*30)
+		}
+		fade := float32(1 - 0.7*glow)
+		opts.ColorScale.Scale(1, fade, fade, 1)
 	}
 
-	// Position the sprite at its final location
-	opts.GeoM.Translate(finalX, finalY)
+	// Pale yellow while stunned by a parry, so the opening is easy to see
+	if o.state == OrcStateStunned {
+		opts.ColorScale.Scale(1, 1, 0.5, 1)
+	}
 
-	screen.DrawImage(o.sprite, opts)
+	// Scale the sprite up by the archetype's scale, flipped if facing left
+	if flash != nil && o.hitFlash > 0 {
+		camera.DrawFlashingSprite(screen, o.sprite, o.positionX, o.positionY, o.def.Scale, o.facingLeft, opts, flash, o.hitFlash/o.tuning.Juice.FlashDuration)
+		return
+	}
+	camera.DrawSprite(screen, o.sprite, o.positionX, o.positionY, o.def.Scale, o.facingLeft, opts)
 }
 
-// GetBounds returns the collision bounds of the orc (adjusted for actual character size)
+// GetBounds returns the collision bounds of the orc in world coordinates (adjusted for actual character size)
 func (o *Orc) GetBounds() (x, y, width, height float64) {
-	const scale = 10.0
-	spriteWidth := float64(o.sprite.Bounds().Dx()) * scale
-	spriteHeight := float64(o.sprite.Bounds().Dy()) * scale
+	scale := o.def.Scale
 
-	// Smaller collision box - only the core body area (8x8 pixels scaled up)
+	// Smaller collision box - only the core body area (scaled up)
 	// This makes it harder for the orc to hit the player
-	charWidth := 8.0 * scale  // Smaller character width (scaled)
-	charHeight := 8.0 * scale // Smaller character height (scaled)
+	charWidth := o.def.BodySize * scale  // Smaller character width (scaled)
+	charHeight := o.def.BodySize * scale // Smaller character height (scaled)
 
-	// Center the collision box within the sprite bounds
-	finalX := (float64(screenWidth)-spriteWidth)/2 + o.positionX + (spriteWidth-charWidth)/2
-	finalY := (float64(screenHeight)-spriteHeight)/2 + o.positionY + (spriteHeight-charHeight)/2
+	// Center the collision box on the orc
+	return o.positionX - charWidth/2, o.positionY - charHeight/2, charWidth, charHeight
+}
 
-	return finalX, finalY, charWidth, charHeight
+// playerBodyBounds returns the player's collision box in world coordinates
+func (o *Orc) playerBodyBounds(playerX, playerY float64) (x, y, width, height float64) {
+	return playerBodyBounds(o.tuning, playerX, playerY)
 }
 
-// CheckCollisionWithPlayer checks if the orc collides with the player (for damage to player)
+// CheckCollisionWithPlayer checks if the orc's body overlaps the player's body
 func (o *Orc) CheckCollisionWithPlayer(playerX, playerY float64) bool {
 	// Get orc bounds (already adjusted for character size)
 	orcX, orcY, orcW, orcH := o.GetBounds()
+	playerFinalX, playerFinalY, playerCharW, playerCharH := o.playerBodyBounds(playerX, playerY)
+
+	// Simple AABB collision detection, for fighters on the same line of the arena
+	return boxesOverlap(playerFinalX, playerFinalY, playerCharW, playerCharH, orcX, orcY, orcW, orcH) &&
+		withinHitDepth(o.tuning, o.positionY, playerY)
+}
+
+// CheckAttackHitPlayer checks if the orc's swing connects with the player.
+// Only the active frames of the attack can hit, and each swing hits at most once.
+// It returns the damage and knockback to apply.
+func (o *Orc) CheckAttackHitPlayer(playerX, playerY float64) (damage, knockback float64, hit bool) {
+	if o.attackHasHit || !o.isAttackActive() || o.def.Projectile != nil {
+		return 0, 0, false
+	}
+	attack := o.currentAttack()
+
+	// The hit box reaches forward from the orc's center in the direction it faces.
+	// Vertically, the swing connects if the player is within hitting depth.
+	reach := attack.Reach * o.def.Scale
+	hitX := o.positionX
+	if o.facingLeft {
+		hitX = o.positionX - reach
+	}
+
+	playerFinalX, _, playerCharW, _ := o.playerBodyBounds(playerX, playerY)
+	if playerFinalX < hitX+reach &&
+		playerFinalX+playerCharW > hitX &&
+		withinHitDepth(o.tuning, o.positionY, playerY) {
+		o.attackHasHit = true
+		return attack.Damage, attack.Knockback, true
+	}
+
+	return 0, 0, false
+}
+
+// ReleaseProjectile returns the projectile a ranged enemy throws when its attack
+// reaches its active frames. Each swing throws at most once. Lobbed projectiles
+// are aimed to come down on the player's current position.
+func (o *Orc) ReleaseProjectile(playerX float64) (*Projectile, bool) {
+	if o.def.Projectile == nil || o.attackHasHit || !o.isAttackActive() {
+		return nil, false
+	}
+	o.attackHasHit = true
+	def := o.def.Projectile
+	attack := o.currentAttack()
+
+	direction := 1.0
+	if o.facingLeft {
+		direction = -1
+	}
+
+	// Launch from about shoulder height
+	startX := o.positionX + direction*o.def.BodySize*o.def.Scale/2
+	startY := o.positionY - o.def.BodySize*o.def.Scale*0.25
+
+	// For a lob, pick the upward speed that brings it back to launch height over the player
+	vy := 0.0
+	if def.Gravity > 0 {
+		flightTicks := math.Abs(playerX-startX) / def.Speed
+		vy = -def.Gravity * flightTicks / 2
+	}
 
-	// Calculate player bounds with accurate character size
-	const scale = 10.0
-	spriteW := 100.0 * scale // Full sprite width
-	spriteH := 100.0 * scale // Full sprite height
-
-	// Player character collision box - smaller for more precise collision (8x8 pixels scaled up)
-	// This matches the orc's collision box size for consistency
-	playerCharW := 8.0 * scale // Smaller character width (scaled)
-	playerCharH := 8.0 * scale // Smaller character height (scaled)
-
-	// Calculate player sprite position (same as in main.go)
-	playerSpriteX := (float64(screenWidth)-spriteW)/2 + playerX
-	playerSpriteY := (float64(screenHeight)-spriteH)/2 + float64(screenHeight)*0.2
-
-	// Center the collision box within the player sprite bounds
-	playerFinalX := playerSpriteX + (spriteW-playerCharW)/2
-	playerFinalY := playerSpriteY + (spriteH-playerCharH)/2
-
-	// Simple AABB collision detection
-	return playerFinalX < orcX+orcW &&
-		playerFinalX+playerCharW > orcX &&
-		playerFinalY < orcY+orcH &&
-		playerFinalY+playerCharH > orcY
+	return &Projectile{
+		X:         startX,
+		Y:         startY,
+		Depth:     o.positionY,
+		VX:        direction * def.Speed,
+		VY:        vy,
+		Gravity:   def.Gravity,
+		Lifetime:  def.Lifetime,
+		Owner:     OwnerEnemy,
+		Damage:    attack.Damage,
+		Knockback: attack.Knockback,
+		Width:     def.Size[0],
+		Height:    def.Size[1],
+		Spin:      direction * def.Spin,
+		Color:     def.Color,
+	}, true
 }
 
 // CheckCollisionWithPlayerAttack checks if the orc is within the player's attack range and direction
-func (o *Orc) CheckCollisionWithPlayerAttack(playerX, playerY float64, facingLeft bool) bool {
+func (o *Orc) CheckCollisionWithPlayerAttack(playerX, playerY float64, reach [2]float64, facingLeft bool) bool {
 	// Get orc bounds (already adjusted for character size)
 	orcX, orcY, orcW, orcH := o.GetBounds()
+	attackX, attackY, attackW, attackH := playerAttackBounds(reach, playerX, playerY, facingLeft)
 
-	// Calculate player bounds with directional attack range
-	const scale = 10.0
-	spriteW := 100.0 * scale // Full sprite width
-	spriteH := 100.0 * scale // Full sprite height
-
-	// Player attack range - larger than collision box (15x15 pixels scaled up)
-	// This allows the player to hit the orc from a safer distance
-	attackRangeW := 15.0 * scale // Larger attack width (scaled)
-	attackRangeH := 15.0 * scale // Larger attack height (scaled)
-
-	// Calculate player sprite position (same as in main.go)
-	playerSpriteX := (float64(screenWidth)-spriteW)/2 + playerX
-	playerSpriteY := (float64(screenHeight)-spriteH)/2 + float64(screenHeight)*0.2
-
-	// Position attack range based on facing direction
-	var playerAttackX, playerAttackY float64
-	if facingLeft {
-		// Attack range is to the left of the player
-		playerAttackX = playerSpriteX + (spriteW-attackRangeW)/2 - attackRangeW/2
-	} else {
-		// Attack range is to the right of the player
-		playerAttackX = playerSpriteX + (spriteW-attackRangeW)/2 + attackRangeW/2
-	}
-	playerAttackY = playerSpriteY + (spriteH-attackRangeH)/2
+	// Simple AABB collision detection for directional attack range, for fighters on the same line of the arena
+	return boxesOverlap(attackX, attackY, attackW, attackH, orcX, orcY, orcW, orcH) &&
+		withinHitDepth(o.tuning, o.positionY, playerY)
+}
 
-	// Simple AABB collision detection for directional attack range
-	return playerAttackX < orcX+orcW &&
-		playerAttackX+attackRangeW > orcX &&
-		playerAttackY < orcY+orcH &&
-		playerAttackY+attackRangeH > orcY
+// Hit describes a blow landing on an enemy
+type Hit struct {
+	SourceX   float64 // Where the hit came from, which decides the knockback direction
+	Damage    int
+	Knockback float64 // Knockback velocity before the enemy's resistance
+	Knockdown bool    // Knocks the enemy off its feet
+	Critical  bool    // Damage was boosted by a critical hit
 }
 
-// TakeDamage handles the orc taking damage from player attacks
-func (o *Orc) TakeDamage(attackerX float64) {
-	// Don't take damage if already hurt or dead
-	if o.state == OrcStateHurt || o.state == OrcStateDeath {
+// TakeDamage handles the orc taking a hit from the player
+func (o *Orc) TakeDamage(hit Hit) {
+	// Don't take damage if dead or down, or still recoiling from a block.
+	// Hurt orcs can be hit again so combos connect; each swing only hits once.
+	if o.state == OrcStateDeath || o.state == OrcStateKnockedDown || o.blockTimer > 0 {
+		return
+	}
+
+	// Direction the hit pushes the orc: away from the attacker
+	pushDir := -1.0
+	if hit.SourceX < o.positionX {
+		pushDir = 1.0
+	}
+	knockback := pushDir * hit.Knockback * (1 - o.def.KnockbackResistance)
+
+	// A shield blocks hits from the front until it breaks
+	attackerInFront := (hit.SourceX < o.positionX) == o.facingLeft
+	if o.shieldHits > 0 && attackerInFront {
+		o.shieldHits--
+		o.blockTimer = o.def.HurtDuration
+		o.knockbackX = pushDir * hit.Knockback * 0.25 // Small shove from the blocked hit
 		return
 	}
 
-	o.health--
+	o.health -= hit.Damage
+	o.ai.TookHit = true // Let the brain react to the hit
+	o.hitFlash = o.tuning.Juice.FlashDuration
 
 	if o.health <= 0 {
 		// Orc dies
 		o.setState(OrcStateDeath)
-		o.deathTimer = 3.0 // Wait 3 seconds before flashing
+		o.deathTimer = o.tuning.Enemy.DeathDelay // Wait before flashing
+	} else if o.def.Boss != nil {
+		// Bosses don't flinch, so a hit never interrupts a special attack.
+		// They ignore further hits for a moment instead of entering the hurt state.
+		o.blockTimer = o.def.HurtDuration
+		o.knockbackX = knockback
+	} else if o.state == OrcStateStunned && !hit.Knockdown {
+		// A stunned orc stays stunned, it just gets pushed around
+		o.knockbackX = knockback
+	} else if hit.Knockdown {
+		// Finishers knock the orc flat for a while
+		o.setState(OrcStateKnockedDown)
+		o.hurtTimer = o.tuning.Enemy.KnockdownDuration
+		o.knockbackX = knockback
 	} else {
-		// Orc gets hurt
+		// Orc gets hurt (a new hit while hurt extends the stun)
 		o.setState(OrcStateHurt)
-		o.hurtTimer = 0.5 // Hurt state lasts 0.5 seconds
-
-		// Apply knockback away from attacker
-		if attackerX < o.positionX {
-			// Attacker is to the left, knock orc right
-			o.knockbackX = 30
-		} else {
-			// Attacker is to the right, knock orc left
-			o.knockbackX = -30
-		}
+		o.hurtTimer = o.def.HurtDuration
+
+		// Apply knockback away from attacker, reduced by the archetype's resistance
+		o.knockbackX = knockback
+	}
+}
+
+// Stun leaves the orc reeling for the given number of seconds, interrupting
+// whatever it was doing. Used when the player parries its attack.
+func (o *Orc) Stun(duration float64) {
+	if o.state == OrcStateDeath || o.state == OrcStateKnockedDown {
+		return
+	}
+	if o.state == OrcStateSpecial {
+		// A parried special still has to recharge
+		o.specialTimer = o.currentSpecial().Every
+	}
+	o.attackHasHit = true // The interrupted swing can't land anymore
+	o.knockbackX = 0
+	o.setState(OrcStateStunned)
+	o.hurtTimer = duration
+}
+
+// updateBossPhase moves the boss on to the last phase its health has reached
+func (o *Orc) updateBossPhase() {
+	phases := o.def.Boss.Phases
+	fraction := float64(o.health) / float64(o.maxHealth)
+	for o.bossPhase+1 < len(phases) && fraction <= phases[o.bossPhase+1].HealthBelow {
+		o.bossPhase++
+		// Start the new phase with its special attack ready
+		o.specialTimer = 0
+	}
+}
+
+// BossPhase returns the index of the boss's current phase
+func (o *Orc) BossPhase() int {
+	return o.bossPhase
+}
+
+// crowdBody returns the orc's body for the crowd separation pass.
+// Knockback resistance makes an orc heavier, so brutes barely budge.
+func (o *Orc) crowdBody(index int) CrowdBody {
+	return CrowdBody{
+		Index:     index,
+		X:         o.positionX,
+		Y:         o.positionY,
+		HalfWidth: o.def.BodySize * o.def.Scale / 2,
+		Mass:      1 + 4*o.def.KnockbackResistance,
+		VelocityX: o.knockbackX,
 	}
 }
 
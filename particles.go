@@ -0,0 +1,327 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+
+	"rpg_demo/aseprite"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// particlesPath is the location of the particle data file
+const particlesPath = "assets/particles.json"
+
+// maxParticles is the size of the particle pool. Bursts beyond it are cut short
+// rather than allocating, since a screen that full won't miss a few more.
+const maxParticles = 2048
+
+// ParticlePreset describes one kind of particle burst. Ranges are [min, max]
+// and each particle picks its own value in between.
+type ParticlePreset struct {
+	Count    [2]int       `json:"count"`    // Particles per burst
+	Lifetime [2]float64   `json:"lifetime"` // Seconds
+	Speed    [2]float64   `json:"speed"`    // Launch speed in pixels per tick
+	Angle    [2]float64   `json:"angle"`    // Launch direction in degrees for a burst heading right: 0 is right, -90 is up
+	Offset   [2]float64   `json:"offset"`   // Burst origin relative to the event, mirrored for bursts heading left
+	Spread   [2]float64   `json:"spread"`   // Particles appear up to this far from the origin on each axis
+	Gravity  float64      `json:"gravity"`  // Added to vertical speed every tick
+	Drag     float64      `json:"drag"`     // Fraction of speed kept every tick, 1 keeps it all
+	Size     [2]float64   `json:"size"`     // Size at birth and at death: pixels, or a scale for sprite particles
+	Colors   [][4]float64 `json:"colors"`   // RGBA over the particle's life, spaced evenly from birth to death
+	Sprite   string       `json:"sprite"`   // Optional .aseprite file whose frames play over the particle's life
+	Tag      string       `json:"tag"`      // Animation tag in Sprite; empty plays every frame
+}
+
+// ParticleConfig is everything in particlesPath: named presets, and which
+// presets burst when each kind of combat event happens
+type ParticleConfig struct {
+	Presets map[string]*ParticlePreset `json:"presets"`
+	Effects map[string][]string        `json:"effects"` // Combat event name to preset names
+}
+
+// LoadParticleConfig reads and validates the particle data file
+func LoadParticleConfig(path string) (*ParticleConfig, error) {
+	config := &ParticleConfig{}
+	if err := decodeDataFile(path, "particle", config, config.Validate); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate checks every preset and that effects only name known events and presets
+func (c *ParticleConfig) Validate() error {
+	var errs []error
+
+	// Sorted so errors come out in the same order every time
+	for _, name := range sortedKeys(c.Presets) {
+		if err := c.Presets[name].Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("preset %q: %w", name, err))
+		}
+	}
+
+	for _, event := range sortedKeys(c.Effects) {
+		if _, ok := combatEventNames[event]; !ok {
+			errs = append(errs, fmt.Errorf("effects: unknown event %q", event))
+		}
+		for _, preset := range c.Effects[event] {
+			if _, ok := c.Presets[preset]; !ok {
+				errs = append(errs, fmt.Errorf("effects: %s uses unknown preset %q", event, preset))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks that the preset's ranges make sense
+func (p *ParticlePreset) Validate() error {
+	var errs []error
+	if p.Count[0] < 1 || p.Count[1] < p.Count[0] {
+		errs = append(errs, fmt.Errorf("count must be at least 1 with min <= max, got %v", p.Count))
+	}
+	if p.Lifetime[0] <= 0 || p.Lifetime[1] < p.Lifetime[0] {
+		errs = append(errs, fmt.Errorf("lifetime must be positive with min <= max, got %v", p.Lifetime))
+	}
+	if p.Speed[0] < 0 || p.Speed[1] < p.Speed[0] {
+		errs = append(errs, fmt.Errorf("speed cannot be negative and needs min <= max, got %v", p.Speed))
+	}
+	if p.Angle[1] < p.Angle[0] {
+		errs = append(errs, fmt.Errorf("angle needs min <= max, got %v", p.Angle))
+	}
+	if p.Spread[0] < 0 || p.Spread[1] < 0 {
+		errs = append(errs, fmt.Errorf("spread cannot be negative, got %v", p.Spread))
+	}
+	if p.Drag <= 0 || p.Drag > 1 {
+		errs = append(errs, fmt.Errorf("drag must be in (0, 1], got %v", p.Drag))
+	}
+	if p.Size[0] < 0 || p.Size[1] < 0 {
+		errs = append(errs, fmt.Errorf("size cannot be negative, got %v", p.Size))
+	}
+	if len(p.Colors) == 0 {
+		errs = append(errs, errors.New("needs at least one color"))
+	}
+	for _, c := range p.Colors {
+		for _, v := range c {
+			if v < 0 || v > 1 {
+				errs = append(errs, fmt.Errorf("color components must be in [0, 1], got %v", c))
+				break
+			}
+		}
+	}
+	if p.Tag != "" && p.Sprite == "" {
+		errs = append(errs, fmt.Errorf("tag %q needs a sprite", p.Tag))
+	}
+	return errors.Join(errs...)
+}
+
+// particlePreset is a loaded preset with its sprite frames, if it has any
+type particlePreset struct {
+	*ParticlePreset
+	frames []*ebiten.Image
+}
+
+// Particle is one live particle in the pool. Positions are in world coordinates.
+type Particle struct {
+	X, Y     float64
+	VX, VY   float64
+	Age      float64
+	Lifetime float64
+	Flip     bool // Mirrors sprite particles from bursts heading left
+	preset   *particlePreset
+}
+
+// ParticleSystem bursts particles when combat events happen. Particles are
+// purely visual, so they draw from their own random source and never touch the
+// game's, keeping replays identical whatever the effects look like.
+type ParticleSystem struct {
+	effects   map[CombatEventKind][]*particlePreset
+	particles []Particle // Pool; the first live entries are in use
+	live      int
+	rng       *rand.Rand
+}
+
+// NewParticleSystem creates a particle system for the given presets, loading any sprites they use
+func NewParticleSystem(config *ParticleConfig, seed uint64) (*ParticleSystem, error) {
+	s := &ParticleSystem{
+		particles: make([]Particle, maxParticles),
+		rng:       rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15)),
+	}
+	if err := s.Reload(config); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload swaps in new presets. Particles already in flight keep their old look.
+func (s *ParticleSystem) Reload(config *ParticleConfig) error {
+	sheets := map[string]*SpriteSheet{}
+	presets := map[string]*particlePreset{}
+	for name, preset := range config.Presets {
+		loaded := &particlePreset{ParticlePreset: preset}
+		if preset.Sprite != "" {
+			frames, err := loadParticleFrames(sheets, preset.Sprite, preset.Tag)
+			if err != nil {
+				return fmt.Errorf("preset %q: %w", name, err)
+			}
+			loaded.frames = frames
+		}
+		presets[name] = loaded
+	}
+
+	effects := map[CombatEventKind][]*particlePreset{}
+	for event, names := range config.Effects {
+		kind := combatEventNames[event]
+		for _, name := range names {
+			effects[kind] = append(effects[kind], presets[name])
+		}
+	}
+	s.effects = effects
+	return nil
+}
+
+// loadParticleFrames returns the frames of a tag in an .aseprite file, or every
+// frame if tag is empty. Files are cached in sheets so presets can share them.
+func loadParticleFrames(sheets map[string]*SpriteSheet, path, tag string) ([]*ebiten.Image, error) {
+	sheet, ok := sheets[path]
+	if !ok {
+		file, err := aseprite.LoadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", path, err)
+		}
+		sheet = &SpriteSheet{File: file, frames: make([]*ebiten.Image, len(file.Frames))}
+		sheets[path] = sheet
+	}
+
+	from, to := 0, len(sheet.File.Frames)-1
+	if tag != "" {
+		if from, to, ok = sheet.TagRange(tag); !ok {
+			return nil, fmt.Errorf("%s has no tag %q", path, tag)
+		}
+	}
+	var frames []*ebiten.Image
+	for index := from; index <= to; index++ {
+		frame, err := sheet.Frame(index)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		frames = append(frames, frame)
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("%s has no frames", path)
+	}
+	return frames, nil
+}
+
+// OnCombatEvent bursts the presets configured for the event. It's a CombatEvents listener.
+func (s *ParticleSystem) OnCombatEvent(event CombatEvent) {
+	for _, preset := range s.effects[event.Kind] {
+		s.Burst(preset, event.X, event.Y, event.Direction)
+	}
+}
+
+// Burst emits a preset's particles at a world position. direction is 1 for a
+// burst heading right and -1 for one heading left.
+func (s *ParticleSystem) Burst(preset *particlePreset, x, y, direction float64) {
+	left := direction < 0
+	count := preset.Count[0] + s.rng.IntN(preset.Count[1]-preset.Count[0]+1)
+	for i := 0; i < count; i++ {
+		if s.live == len(s.particles) {
+			return // Pool is full
+		}
+
+		angle := s.between(preset.Angle) * math.Pi / 180
+		offsetX := preset.Offset[0]
+		if left {
+			angle = math.Pi - angle
+			offsetX = -offsetX
+		}
+		speed := s.between(preset.Speed)
+
+		s.particles[s.live] = Particle{
+			X:        x + offsetX + (s.rng.Float64()*2-1)*preset.Spread[0],
+			Y:        y + preset.Offset[1] + (s.rng.Float64()*2-1)*preset.Spread[1],
+			VX:       math.Cos(angle) * speed,
+			VY:       math.Sin(angle) * speed,
+			Lifetime: s.between(preset.Lifetime),
+			Flip:     left,
+			preset:   preset,
+		}
+		s.live++
+	}
+}
+
+// between picks a random value in a [min, max] range
+func (s *ParticleSystem) between(r [2]float64) float64 {
+	return r[0] + s.rng.Float64()*(r[1]-r[0])
+}
+
+// Update moves and ages every particle, returning expired ones to the pool
+func (s *ParticleSystem) Update() {
+	const dt = 1.0 / 60.0 // Assuming 60 FPS
+	for i := 0; i < s.live; {
+		p := &s.particles[i]
+		p.Age += dt
+		if p.Age >= p.Lifetime {
+			// Swap the last live particle into this slot and look at it next
+			s.live--
+			*p = s.particles[s.live]
+			s.particles[s.live].preset = nil
+			continue
+		}
+
+		p.VY += p.preset.Gravity
+		p.VX *= p.preset.Drag
+		p.VY *= p.preset.Drag
+		p.X += p.VX
+		p.Y += p.VY
+		i++
+	}
+}
+
+// Draw renders every live particle through the camera. Plain particles are
+// squares drawn with a 1x1 white pixel image.
+func (s *ParticleSystem) Draw(screen, pixel *ebiten.Image, camera *Camera) {
+	opts := &ebiten.DrawImageOptions{}
+	for i := 0; i < s.live; i++ {
+		p := &s.particles[i]
+		life := p.Age / p.Lifetime
+		size := p.preset.Size[0] + (p.preset.Size[1]-p.preset.Size[0])*life
+		r, g, b, a := particleColor(p.preset.Colors, life)
+
+		opts.ColorScale.Reset()
+		opts.ColorScale.Scale(r, g, b, 1)
+		opts.ColorScale.ScaleAlpha(a)
+
+		if frames := p.preset.frames; frames != nil {
+			frame := frames[min(int(life*float64(len(frames))), len(frames)-1)]
+			camera.DrawSprite(screen, frame, p.X, p.Y, size, p.Flip, opts)
+			continue
+		}
+
+		opts.GeoM.Reset()
+		opts.GeoM.Scale(size, size)
+		opts.GeoM.Translate(p.X-size/2, p.Y-size/2)
+		camera.Apply(&opts.GeoM)
+		screen.DrawImage(pixel, opts)
+	}
+}
+
+// particleColor blends between the colors either side of a point in a particle's
+// life, with the colors spaced evenly from birth (0) to death (1)
+func particleColor(colors [][4]float64, life float64) (r, g, b, a float32) {
+	if len(colors) == 1 {
+		c := colors[0]
+		return float32(c[0]), float32(c[1]), float32(c[2]), float32(c[3])
+	}
+
+	position := life * float64(len(colors)-1)
+	index := min(int(position), len(colors)-2)
+	t := position - float64(index)
+	from, to := colors[index], colors[index+1]
+	blend := func(i int) float32 {
+		return float32(from[i] + (to[i]-from[i])*t)
+	}
+	return blend(0), blend(1), blend(2), blend(3)
+}
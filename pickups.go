@@ -0,0 +1,333 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+
+	"rpg_demo/aseprite"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const pickupsPath = "assets/pickups.json"
+
+// pickupSize is the size of a pickup icon in sprite pixels
+const pickupSize = 16
+
+// pickupGroundOffset is how far below a fighter's center a dropped pickup
+// lies, so it sits on the floor at the fighter's feet
+const pickupGroundOffset = 40.0
+
+// PickupDefinition describes an item the player can collect. It can heal,
+// grant a buff, swap the player's weapon, or any mix of those.
+type PickupDefinition struct {
+	Name       string  `json:"name"`       // Shown when it's collected
+	Icon       string  `json:"icon"`       // Tag of its icon in the pickup sprite
+	Heal       float64 `json:"heal"`       // Health restored
+	Buff       string  `json:"buff"`       // Stat the buff modifies, see buffStats; empty for no buff
+	Multiplier float64 `json:"multiplier"` // Buff multiplier for the stat, unused for "invulnerable"
+	Duration   float64 `json:"duration"`   // Seconds the buff lasts
+	Weapon     string  `json:"weapon"`     // Weapon it equips, see weaponsPath; empty to keep the current one
+}
+
+// LootTable decides what an enemy drops when it dies
+type LootTable struct {
+	Chance float64    `json:"chance"` // Probability of dropping anything at all, 0 to 1
+	Drops  []LootDrop `json:"drops"`
+}
+
+// LootDrop is one possible drop in a loot table
+type LootDrop struct {
+	Pickup string  `json:"pickup"`
+	Weight float64 `json:"weight"` // Relative likelihood among the table's drops
+}
+
+// PickupConfig is everything in pickupsPath
+type PickupConfig struct {
+	Sprite    string  `json:"sprite"`    // .aseprite file with a tag per icon
+	Scale     float64 `json:"scale"`     // How much icons are scaled up in the world
+	Lifetime  float64 `json:"lifetime"`  // Seconds a pickup lies on the ground before vanishing
+	BlinkTime float64 `json:"blinkTime"` // Seconds before vanishing that it starts blinking
+
+	Pickups    map[string]*PickupDefinition `json:"pickups"`
+	LootTables map[string]*LootTable        `json:"lootTables"`
+	EnemyLoot  map[string]string            `json:"enemyLoot"` // Enemy type to loot table; enemies not listed drop nothing
+}
+
+// LoadPickupConfig reads and validates the pickup data file.
+// isKnownType and isKnownWeapon report whether an enemy type or weapon name exists.
+func LoadPickupConfig(path string, isKnownType, isKnownWeapon func(string) bool) (*PickupConfig, error) {
+	config := &PickupConfig{}
+	if err := decodeDataFile(path, "pickup", config, func() error {
+		return config.Validate(isKnownType, isKnownWeapon)
+	}); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate checks every pickup and loot table, and that they only name known pickups, tables, enemies and weapons
+func (c *PickupConfig) Validate(isKnownType, isKnownWeapon func(string) bool) error {
+	var errs []error
+	if c.Sprite == "" {
+		errs = append(errs, errors.New("sprite is required"))
+	}
+	if c.Scale <= 0 {
+		errs = append(errs, fmt.Errorf("scale must be positive, got %v", c.Scale))
+	}
+	if c.Lifetime <= 0 {
+		errs = append(errs, fmt.Errorf("lifetime must be positive, got %v", c.Lifetime))
+	}
+	if c.BlinkTime < 0 || c.BlinkTime > c.Lifetime {
+		errs = append(errs, fmt.Errorf("blinkTime must be between 0 and the lifetime, got %v", c.BlinkTime))
+	}
+
+	// Sorted so errors come out in the same order every time
+	for _, name := range sortedKeys(c.Pickups) {
+		if err := c.Pickups[name].Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("pickup %q: %w", name, err))
+		}
+		if weapon := c.Pickups[name].Weapon; weapon != "" && !isKnownWeapon(weapon) {
+			errs = append(errs, fmt.Errorf("pickup %q: unknown weapon %q", name, weapon))
+		}
+	}
+
+	for _, name := range sortedKeys(c.LootTables) {
+		table := c.LootTables[name]
+		if table.Chance < 0 || table.Chance > 1 {
+			errs = append(errs, fmt.Errorf("loot table %q: chance must be between 0 and 1, got %v", name, table.Chance))
+		}
+		if len(table.Drops) == 0 {
+			errs = append(errs, fmt.Errorf("loot table %q: needs at least one drop", name))
+		}
+		for _, drop := range table.Drops {
+			if _, ok := c.Pickups[drop.Pickup]; !ok {
+				errs = append(errs, fmt.Errorf("loot table %q: unknown pickup %q", name, drop.Pickup))
+			}
+			if drop.Weight <= 0 {
+				errs = append(errs, fmt.Errorf("loot table %q: weight of %q must be positive, got %v", name, drop.Pickup, drop.Weight))
+			}
+		}
+	}
+
+	for _, enemy := range sortedKeys(c.EnemyLoot) {
+		if !isKnownType(enemy) {
+			errs = append(errs, fmt.Errorf("enemyLoot: unknown enemy type %q", enemy))
+		}
+		if table := c.EnemyLoot[enemy]; c.LootTables[table] == nil {
+			errs = append(errs, fmt.Errorf("enemyLoot: %q uses unknown loot table %q", enemy, table))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate checks that the pickup does something and its buff makes sense
+func (d *PickupDefinition) Validate() error {
+	var errs []error
+	if d.Icon == "" {
+		errs = append(errs, errors.New("icon is required"))
+	}
+	if d.Heal < 0 {
+		errs = append(errs, fmt.Errorf("heal cannot be negative, got %v", d.Heal))
+	}
+	if d.Buff == "" {
+		if d.Heal == 0 && d.Weapon == "" {
+			errs = append(errs, errors.New("needs heal, a buff or a weapon"))
+		}
+		return errors.Join(errs...)
+	}
+
+	stat, ok := buffStats[d.Buff]
+	if !ok {
+		errs = append(errs, fmt.Errorf("unknown buff %q", d.Buff))
+	}
+	if d.Duration <= 0 {
+		errs = append(errs, fmt.Errorf("duration must be positive, got %v", d.Duration))
+	}
+	if ok && stat != BuffInvulnerable && d.Multiplier <= 0 {
+		errs = append(errs, fmt.Errorf("multiplier must be positive, got %v", d.Multiplier))
+	}
+	return errors.Join(errs...)
+}
+
+// sortedKeys returns a map's keys in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Roll picks a drop from the table, or reports false if nothing drops.
+// It always draws twice from rng so the random sequence doesn't depend on the outcome.
+func (t *LootTable) Roll(rng *rand.Rand) (string, bool) {
+	dropRoll, pickRoll := rng.Float64(), rng.Float64()
+	if dropRoll >= t.Chance {
+		return "", false
+	}
+
+	total := 0.0
+	for _, drop := range t.Drops {
+		total += drop.Weight
+	}
+	pick := pickRoll * total
+	for _, drop := range t.Drops {
+		if pick < drop.Weight {
+			return drop.Pickup, true
+		}
+		pick -= drop.Weight
+	}
+	return t.Drops[len(t.Drops)-1].Pickup, true // Only reached through rounding
+}
+
+// LoadPickupIcons loads the icon of every pickup and weapon from the pickup sprite, by icon tag
+func LoadPickupIcons(config *PickupConfig, weapons *WeaponConfig) (map[string]*ebiten.Image, error) {
+	file, err := aseprite.LoadFile(config.Sprite)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", config.Sprite, err)
+	}
+	sheet := &SpriteSheet{File: file, frames: make([]*ebiten.Image, len(file.Frames))}
+
+	icons := map[string]*ebiten.Image{}
+	load := func(owner, tag string) error {
+		if _, ok := icons[tag]; ok {
+			return nil
+		}
+		from, _, ok := sheet.TagRange(tag)
+		if !ok {
+			return fmt.Errorf("%s: %s has no tag %q", owner, config.Sprite, tag)
+		}
+		if icons[tag], err = sheet.Frame(from); err != nil {
+			return fmt.Errorf("%s: %s: %w", owner, config.Sprite, err)
+		}
+		return nil
+	}
+	for _, name := range sortedKeys(config.Pickups) {
+		if err := load(fmt.Sprintf("pickup %q", name), config.Pickups[name].Icon); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range sortedKeys(weapons.Weapons) {
+		if err := load(fmt.Sprintf("weapon %q", name), weapons.Weapons[name].Icon); err != nil {
+			return nil, err
+		}
+	}
+	return icons, nil
+}
+
+// Pickup is an item lying in the arena. Positions are in world coordinates;
+// Depth is the line of the arena it lies on, like a fighter's positionY.
+type Pickup struct {
+	X, Depth float64
+	Type     string
+	Age      float64
+}
+
+// dropLoot rolls a killed enemy's loot table and leaves the drop, if any, where it fell
+func (g *Game) dropLoot(orc *Orc) {
+	table, ok := g.pickupConfig.LootTables[g.pickupConfig.EnemyLoot[orc.enemyType]]
+	if !ok {
+		return
+	}
+	if pickup, dropped := table.Roll(g.rng); dropped {
+		g.pickups = append(g.pickups, &Pickup{X: orc.positionX, Depth: orc.positionY, Type: pickup})
+	}
+}
+
+// updatePickups ages pickups, removes expired ones and collects any the player walks over
+func (g *Game) updatePickups() {
+	const dt = 1.0 / 60.0 // Assuming 60 FPS
+	g.buffs.Update()
+
+	alive := g.playerState == PlayerStateAlive || g.playerState == PlayerStateHurt
+	bodyX, bodyY, bodyW, bodyH := playerBodyBounds(g.tuning, g.positionX, g.positionY)
+
+	remaining := g.pickups[:0]
+	for _, p := range g.pickups {
+		p.Age += dt
+		def := g.pickupConfig.Pickups[p.Type]
+		if p.Age >= g.pickupConfig.Lifetime || def == nil {
+			continue // Vanished, or removed from the data by a reload
+		}
+
+		if alive && withinHitDepth(g.tuning, p.Depth, g.positionY) {
+			x, y, w, h := g.pickupBounds(p)
+			if boxesOverlap(bodyX, bodyY, bodyW, bodyH, x, y, w, h) {
+				g.collectPickup(p, def)
+				continue
+			}
+		}
+		remaining = append(remaining, p)
+	}
+	clear(g.pickups[len(remaining):])
+	g.pickups = remaining
+}
+
+// pickupBounds returns a pickup's collection box in world coordinates
+func (g *Game) pickupBounds(p *Pickup) (x, y, width, height float64) {
+	size := pickupSize * g.pickupConfig.Scale
+	return p.X - size/2, p.Depth + pickupGroundOffset - size/2, size, size
+}
+
+// collectPickup applies a pickup's healing and buff
+func (g *Game) collectPickup(p *Pickup, def *PickupDefinition) {
+	g.events.Emit(CombatEvent{Kind: EventPickup, X: p.X, Y: p.Depth + pickupGroundOffset, Name: def.Name})
+
+	if def.Heal > 0 {
+		healed := math.Min(def.Heal, g.maxHealth()-g.playerHealth)
+		if healed > 0 {
+			g.playerHealth += healed
+			g.events.Emit(CombatEvent{Kind: EventPlayerHealed, X: g.positionX, Y: g.positionY, Amount: healed})
+		}
+	}
+
+	if def.Buff != "" {
+		g.buffs.Add(Buff{
+			Source:     p.Type,
+			Icon:       def.Icon,
+			Stat:       buffStats[def.Buff],
+			Multiplier: def.Multiplier,
+			Duration:   def.Duration,
+		})
+	}
+
+	if def.Weapon != "" {
+		g.equipWeapon(def.Weapon)
+	}
+}
+
+// drawPickups draws pickups bobbing on the floor, blinking faster and faster as they're about to vanish
+func (g *Game) drawPickups(screen *ebiten.Image) {
+	config := g.pickupConfig
+	for _, p := range g.pickups {
+		left := config.Lifetime - p.Age
+		if left < config.BlinkTime && !g.settings.Accessibility.ReduceFlashing {
+			interval := 0.2
+			if left < config.BlinkTime/3 {
+				interval = 0.08
+			}
+			if int(left/interval)%2 == 1 {
+				continue
+			}
+		}
+
+		def := config.Pickups[p.Type]
+		if def == nil || g.pickupIcons[def.Icon] == nil {
+			continue // Removed from the data by a reload
+		}
+		icon := g.pickupIcons[def.Icon]
+		opts := &ebiten.DrawImageOptions{}
+		if left < config.BlinkTime && g.settings.Accessibility.ReduceFlashing {
+			// Reduced flashing: fade out instead of blinking
+			opts.ColorScale.ScaleAlpha(float32(0.3 + 0.7*left/config.BlinkTime))
+		}
+		bob := 6 * math.Sin(p.Age*4)
+		g.camera.DrawSprite(screen, icon, p.X, p.Depth+pickupGroundOffset+bob, config.Scale, false, opts)
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+const upgradesPath = "assets/upgrades.json"
+
+// maxUpgradeChoices is how many upgrades the level-up screen has room for
+const maxUpgradeChoices = 4
+
+// PlayerStats are the player's stats as changed by upgrades. Every upgrade
+// adds its amount to one of them, so picking the same upgrade again stacks.
+type PlayerStats struct {
+	Range       float64 // Attack reach multiplier
+	Damage      float64 // Damage added to every hit, before critical hits and buffs
+	AttackSpeed float64 // Swing animation speed multiplier
+	MaxHealth   float64 // Added to the base maximum health
+	Lifesteal   float64 // Health regained per point of damage dealt
+	Knockback   float64 // Knockback multiplier
+	MoveSpeed   float64 // Walking speed multiplier
+}
+
+// newPlayerStats returns the stats of a player without upgrades
+func newPlayerStats() PlayerStats {
+	return PlayerStats{Range: 1, AttackSpeed: 1, Knockback: 1, MoveSpeed: 1}
+}
+
+// upgradeStats names the stats in data files, pointing at the field each one adds to
+var upgradeStats = map[string]func(*PlayerStats) *float64{
+	"range":       func(s *PlayerStats) *float64 { return &s.Range },
+	"damage":      func(s *PlayerStats) *float64 { return &s.Damage },
+	"attackSpeed": func(s *PlayerStats) *float64 { return &s.AttackSpeed },
+	"maxHealth":   func(s *PlayerStats) *float64 { return &s.MaxHealth },
+	"lifesteal":   func(s *PlayerStats) *float64 { return &s.Lifesteal },
+	"knockback":   func(s *PlayerStats) *float64 { return &s.Knockback },
+	"moveSpeed":   func(s *PlayerStats) *float64 { return &s.MoveSpeed },
+}
+
+// Upgrade is one choice offered on leveling up
+type Upgrade struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"` // One short line shown under the name
+	Stat        string  `json:"stat"`        // Stat it adds to, see upgradeStats
+	Amount      float64 `json:"amount"`      // Added to the stat each time it's picked
+	MaxStacks   int     `json:"maxStacks"`   // Times it can be picked in a run; 0 for no limit
+}
+
+// UpgradeConfig is everything in upgradesPath
+type UpgradeConfig struct {
+	Choices      int                 `json:"choices"`      // Upgrades offered per level
+	FirstLevelXP float64             `json:"firstLevelXP"` // XP needed to reach level 2
+	XPGrowth     float64             `json:"xpGrowth"`     // Each level needs this many times the XP of the one before
+	Upgrades     map[string]*Upgrade `json:"upgrades"`
+}
+
+// LoadUpgradeConfig reads and validates the upgrade data file
+func LoadUpgradeConfig(path string) (*UpgradeConfig, error) {
+	config := &UpgradeConfig{}
+	if err := decodeDataFile(path, "upgrade", config, config.Validate); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate checks the level curve and every upgrade
+func (c *UpgradeConfig) Validate() error {
+	var errs []error
+	if c.Choices < 1 || c.Choices > maxUpgradeChoices {
+		errs = append(errs, fmt.Errorf("choices must be from 1 to %d, got %d", maxUpgradeChoices, c.Choices))
+	}
+	if c.FirstLevelXP <= 0 {
+		errs = append(errs, fmt.Errorf("firstLevelXP must be positive, got %v", c.FirstLevelXP))
+	}
+	if c.XPGrowth < 1 {
+		errs = append(errs, fmt.Errorf("xpGrowth must be at least 1, got %v", c.XPGrowth))
+	}
+	if len(c.Upgrades) == 0 {
+		errs = append(errs, errors.New("no upgrades defined"))
+	}
+
+	// Sorted so errors come out in the same order every time
+	for _, name := range sortedKeys(c.Upgrades) {
+		upgrade := c.Upgrades[name]
+		if upgrade.Name == "" {
+			errs = append(errs, fmt.Errorf("upgrade %q: name is required", name))
+		}
+		if _, ok := upgradeStats[upgrade.Stat]; !ok {
+			errs = append(errs, fmt.Errorf("upgrade %q: unknown stat %q", name, upgrade.Stat))
+		}
+		if upgrade.Amount <= 0 {
+			errs = append(errs, fmt.Errorf("upgrade %q: amount must be positive, got %v", name, upgrade.Amount))
+		}
+		if upgrade.MaxStacks < 0 {
+			errs = append(errs, fmt.Errorf("upgrade %q: maxStacks cannot be negative, got %d", name, upgrade.MaxStacks))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// XPToLevel returns the XP needed to go from level to the next one
+func (c *UpgradeConfig) XPToLevel(level int) float64 {
+	return math.Round(c.FirstLevelXP * math.Pow(c.XPGrowth, float64(level-1)))
+}
+
+// maxHealth returns the player's maximum health with upgrades
+func (g *Game) maxHealth() float64 {
+	return g.tuning.Player.MaxHealth + g.stats.MaxHealth
+}
+
+// attackReach returns the current combo step's reach with upgrades
+func (g *Game) attackReach() [2]float64 {
+	reach := g.currentPlayerAttack().Reach
+	return [2]float64{reach[0] * g.stats.Range, reach[1] * g.stats.Range}
+}
+
+// gainXP adds XP for a kill, queueing a level-up for every threshold crossed
+func (g *Game) gainXP(xp int) {
+	g.xp += float64(xp)
+	for need := g.upgradeConfig.XPToLevel(g.playerLevel); g.xp >= need; need = g.upgradeConfig.XPToLevel(g.playerLevel) {
+		g.xp -= need
+		g.playerLevel++
+		g.pendingLevelUps++
+	}
+}
+
+// lifesteal heals the player for damage they dealt
+func (g *Game) lifesteal(damage float64) {
+	healed := math.Min(damage*g.stats.Lifesteal, g.maxHealth()-g.playerHealth)
+	if healed > 0 {
+		g.playerHealth += healed
+		g.events.Emit(CombatEvent{Kind: EventPlayerHealed, X: g.positionX, Y: g.positionY, Amount: healed})
+	}
+}
+
+// updateProgression runs the upgrade choice, reporting whether it is holding
+// the fight. Level-ups are saved up until the wave is cleared and offered
+// between waves, with the player back on their feet, so the choice never
+// cuts into a fight, a hit or a death.
+func (g *Game) updateProgression() bool {
+	between := g.director.InIntermission() && g.playerState == PlayerStateAlive
+	if g.upgradeChoices == nil && g.pendingLevelUps > 0 && between {
+		g.offerUpgrades()
+	}
+	if g.upgradeChoices == nil {
+		return false
+	}
+	g.updateLevelUp()
+	return true
+}
+
+// offerUpgrades picks the choices for the next pending level-up from the
+// upgrades that haven't been maxed out. The fight pauses until one is picked.
+func (g *Game) offerUpgrades() {
+	config := g.upgradeConfig
+	var available []string
+	for _, name := range sortedKeys(config.Upgrades) {
+		if upgrade := config.Upgrades[name]; upgrade.MaxStacks == 0 || g.upgradeStacks[name] < upgrade.MaxStacks {
+			available = append(available, name)
+		}
+	}
+	g.pendingLevelUps--
+	if len(available) == 0 {
+		return // Everything is maxed out, the level-up has nothing to offer
+	}
+
+	g.rng.Shuffle(len(available), func(i, j int) {
+		available[i], available[j] = available[j], available[i]
+	})
+	g.upgradeChoices = available[:min(config.Choices, len(available))]
+	g.upgradeSelected = 0
+
+	// Start from a clean slate so a button held or buffered from the fight doesn't pick at once
+	g.input.Reset(g.input.Held())
+}
+
+// updateLevelUp moves the selection between the offered upgrades and applies the one picked
+func (g *Game) updateLevelUp() {
+	count := len(g.upgradeChoices)
+	if g.input.JustPressed(ActionMoveLeft) || g.input.JustPressed(ActionMoveUp) {
+		g.upgradeSelected = (g.upgradeSelected + count - 1) % count
+	}
+	if g.input.JustPressed(ActionMoveRight) || g.input.JustPressed(ActionMoveDown) {
+		g.upgradeSelected = (g.upgradeSelected + 1) % count
+	}
+	if !g.input.JustPressed(ActionAttack) {
+		return
+	}
+
+	name := g.upgradeChoices[g.upgradeSelected]
+	g.upgradeChoices = nil
+	g.applyUpgrade(name)
+
+	// The confirming press shouldn't also swing once the fight resumes
+	g.input.Reset(g.input.Held())
+}
+
+// applyUpgrade adds an upgrade to the player's stats. Extra maximum health
+// comes already filled, so picking it also heals.
+func (g *Game) applyUpgrade(name string) {
+	upgrade := g.upgradeConfig.Upgrades[name]
+	if upgrade == nil {
+		return // Removed from the data by a reload
+	}
+	g.upgradeStacks[name]++
+	*upgradeStats[upgrade.Stat](&g.stats) += upgrade.Amount
+	if upgrade.Stat == "maxHealth" {
+		g.playerHealth += upgrade.Amount
+	}
+	g.director.Announce(fmt.Sprintf("%s!", upgrade.Name))
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// ProjectileOwner decides who a projectile can hit
+type ProjectileOwner int
+
+const (
+	OwnerEnemy  ProjectileOwner = iota // Thrown by an enemy, hits the player
+	OwnerPlayer                        // Shot or deflected by the player, hits enemies
+)
+
+// deflectSpeedup is how much faster a projectile flies after the player knocks it back
+const deflectSpeedup = 1.3
+
+// Projectile is a thrown weapon in flight. Positions are in world coordinates
+// like enemy positions, and velocities are in pixels per tick.
+type Projectile struct {
+	X, Y      float64
+	Depth     float64 // Line of the arena it was thrown along; only fighters within hitting depth of it are hit
+	VX, VY    float64
+	Gravity   float64 // Added to VY every tick; 0 flies straight
+	Lifetime  float64 // Seconds left before it disappears
+	Owner     ProjectileOwner
+	Damage    float64
+	Knockback float64
+
+	// Look and hitbox
+	Width, Height float64
+	Angle         float64 // Rotation in radians
+	Spin          float64 // Rotation per tick
+	Color         [3]float64
+
+	dead bool
+}
+
+// Update moves the projectile one tick
+func (p *Projectile) Update() {
+	p.VY += p.Gravity
+	p.X += p.VX
+	p.Y += p.VY
+	p.Angle += p.Spin
+
+	p.Lifetime -= 1.0 / 60.0 // Assuming 60 FPS
+	if p.Lifetime <= 0 || p.Y > screenHeight/2 {
+		p.dead = true
+	}
+}
+
+// Bounds returns the projectile's hitbox in world coordinates. The box ignores
+// rotation and uses the larger side, so spinning axes hit the same from every angle.
+func (p *Projectile) Bounds() (x, y, width, height float64) {
+	size := math.Max(p.Width, p.Height)
+	return p.X - size/2, p.Y - size/2, size, size
+}
+
+// CanHitPlayer reports whether the projectile can still hit the player
+func (p *Projectile) CanHitPlayer() bool {
+	return !p.dead && p.Owner == OwnerEnemy
+}
+
+// CanHitEnemies reports whether the projectile can still hit enemies
+func (p *Projectile) CanHitEnemies() bool {
+	return !p.dead && p.Owner == OwnerPlayer
+}
+
+// SourceX returns a point just behind the projectile, so knockback from a hit
+// pushes targets along its direction of travel
+func (p *Projectile) SourceX() float64 {
+	return p.X - math.Copysign(1, p.VX)
+}
+
+// Deflect turns the projectile against the enemies, sending it back faster
+// in the direction the player is facing, as hard as the tuning's deflected hit
+func (p *Projectile) Deflect(facingLeft bool, deflect DeflectTuning) {
+	speed := math.Abs(p.VX) * deflectSpeedup
+	if facingLeft {
+		p.VX = -speed
+	} else {
+		p.VX = speed
+	}
+	p.VY = math.Min(p.VY, 0) // Pop it back up rather than into the ground
+	p.Spin = -p.Spin
+	p.Owner = OwnerPlayer
+	p.Damage = float64(deflect.Damage)
+	p.Knockback = deflect.Knockback
+	p.Lifetime = math.Max(p.Lifetime, 2)
+}
+
+// Draw renders the projectile as a spinning bar through the camera, using a 1x1 white pixel image
+func (p *Projectile) Draw(screen, pixel *ebiten.Image, camera *Camera) {
+	if p.dead {
+		return
+	}
+
+	opts := &ebiten.DrawImageOptions{}
+	opts.GeoM.Scale(p.Width, p.Height)
+	opts.GeoM.Translate(-p.Width/2, -p.Height/2)
+	opts.GeoM.Rotate(p.Angle)
+	opts.GeoM.Translate(p.X, p.Y)
+	camera.Apply(&opts.GeoM)
+	opts.ColorScale.Scale(float32(p.Color[0]), float32(p.Color[1]), float32(p.Color[2]), 1)
+	screen.DrawImage(pixel, opts)
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"os"
+)
+
+// replayMagic identifies replay files
+var replayMagic = [4]byte{'O', 'R', 'C', 'R'}
+
+// replayVersion is the current version of the replay file format
+const replayVersion = 3
+
+// inputResetFlag marks a tick where input state was reset before being applied,
+// which happens on the first tick after leaving the pause menu
+const inputResetFlag ActionSet = 1 << 15
+
+// Replay is a recorded run: everything needed to feed the simulation the same
+// input again, plus the hash of the final state to check the result against
+type Replay struct {
+	Seed       uint64
+	Level      string // Level name, empty for the default level
+	AttackMode AttackMode
+	Ticks      []ActionSet // Input applied on every simulation tick, in order
+	FinalHash  uint64
+}
+
+// Record appends the input applied on one simulation tick
+func (r *Replay) Record(entry ActionSet) {
+	r.Ticks = append(r.Ticks, entry)
+}
+
+// Save writes the replay to path.
+// Ticks are run-length encoded since input rarely changes between ticks.
+//
+// Format (little endian): magic[4] version[1] seed[8] levelLength[uvarint] level[levelLength] attackMode[1] finalHash[8]
+// tickCount[uvarint] then (actions[uvarint] runLength[uvarint]) pairs.
+func (r *Replay) Save(path string) error {
+	var buf bytes.Buffer
+	buf.Write(replayMagic[:])
+	buf.WriteByte(replayVersion)
+	binary.Write(&buf, binary.LittleEndian, r.Seed)
+	buf.Write(binary.AppendUvarint(nil, uint64(len(r.Level))))
+	buf.WriteString(r.Level)
+	if r.AttackMode == AttackModeHold {
+		buf.WriteByte(1)
+	} else {
+		buf.WriteByte(0)
+	}
+	binary.Write(&buf, binary.LittleEndian, r.FinalHash)
+	buf.Write(binary.AppendUvarint(nil, uint64(len(r.Ticks))))
+
+	for i := 0; i < len(r.Ticks); {
+		run := 1
+		for i+run < len(r.Ticks) && r.Ticks[i+run] == r.Ticks[i] {
+			run++
+		}
+		buf.Write(binary.AppendUvarint(nil, uint64(r.Ticks[i])))
+		buf.Write(binary.AppendUvarint(nil, uint64(run)))
+		i += run
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write replay: %w", err)
+	}
+	return nil
+}
+
+// LoadReplay reads a replay file written by Save
+func LoadReplay(path string) (*Replay, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replay: %w", err)
+	}
+	reader := bufio.NewReader(bytes.NewReader(data))
+
+	var magic [4]byte
+	if _, err := io.ReadFull(reader, magic[:]); err != nil || magic != replayMagic {
+		return nil, errors.New("not a replay file")
+	}
+	version, err := reader.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replay header: %w", err)
+	}
+	if version != replayVersion {
+		return nil, fmt.Errorf("unsupported replay version %d", version)
+	}
+
+	r := &Replay{AttackMode: AttackModePress}
+	if err := binary.Read(reader, binary.LittleEndian, &r.Seed); err != nil {
+		return nil, fmt.Errorf("failed to read replay header: %w", err)
+	}
+	levelLength, err := binary.ReadUvarint(reader)
+	if err != nil || levelLength > 256 {
+		return nil, errors.New("failed to read replay header: invalid level name")
+	}
+	level := make([]byte, levelLength)
+	if _, err := io.ReadFull(reader, level); err != nil {
+		return nil, fmt.Errorf("failed to read replay header: %w", err)
+	}
+	r.Level = string(level)
+	mode, err := reader.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replay header: %w", err)
+	}
+	if mode == 1 {
+		r.AttackMode = AttackModeHold
+	}
+	if err := binary.Read(reader, binary.LittleEndian, &r.FinalHash); err != nil {
+		return nil, fmt.Errorf("failed to read replay header: %w", err)
+	}
+
+	tickCount, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replay header: %w", err)
+	}
+	r.Ticks = make([]ActionSet, 0, min(tickCount, 1<<20)) // Don't trust the header for huge allocations
+	for uint64(len(r.Ticks)) < tickCount {
+		actions, err := binary.ReadUvarint(reader)
+		if err != nil {
+			return nil, fmt.Errorf("replay truncated at tick %d: %w", len(r.Ticks), err)
+		}
+		run, err := binary.ReadUvarint(reader)
+		if err != nil {
+			return nil, fmt.Errorf("replay truncated at tick %d: %w", len(r.Ticks), err)
+		}
+		if run == 0 || uint64(len(r.Ticks))+run > tickCount {
+			return nil, fmt.Errorf("invalid run length %d at tick %d", run, len(r.Ticks))
+		}
+		for j := uint64(0); j < run; j++ {
+			r.Ticks = append(r.Ticks, ActionSet(actions))
+		}
+	}
+
+	return r, nil
+}
+
+// StateHash returns a hash of the simulation state. Two runs fed the same seed
+// and input must produce the same hash on every tick.
+func (g *Game) StateHash() uint64 {
+	h := fnv.New64a()
+	writeFloat := func(v float64) {
+		binary.Write(h, binary.LittleEndian, math.Float64bits(v))
+	}
+	writeInt := func(v int) {
+		binary.Write(h, binary.LittleEndian, int64(v))
+	}
+
+	writeInt(int(g.ticks))
+	writeInt(int(g.fightTicks))
+	writeFloat(g.positionX)
+	writeFloat(g.positionY)
+	writeFloat(g.camera.X)
+	writeFloat(g.camera.Zoom)
+	writeFloat(g.playerHealth)
+	writeInt(int(g.playerState))
+	writeInt(g.currentFrame)
+	writeInt(g.comboStep)
+	writeInt(boolInt(g.comboQueued))
+	writeFloat(g.comboTimer)
+	writeInt(g.comboHits)
+	writeInt(boolInt(g.isDodging))
+	writeFloat(g.dodgeTimer)
+	writeFloat(g.dodgeCooldown)
+	writeFloat(g.stamina)
+	writeFloat(g.staminaDelay)
+	writeInt(boolInt(g.facingLeft))
+	writeFloat(g.invulnTimer)
+	writeInt(boolInt(g.isBlocking))
+	writeFloat(g.blockTimer)
+	writeFloat(g.parryCooldown)
+	writeFloat(g.critTimer)
+	writeInt(g.orcsKilled)
+	writeInt(g.score)
+	writeInt(g.director.waveNumber)
+	writeInt(int(g.director.phase))
+	writeFloat(g.director.timer)
+	writeInt(len(g.director.queue))
+	writeInt(len(g.director.pendingBosses))
+	for _, kills := range g.director.nextBossKills {
+		writeInt(kills)
+	}
+
+	writeInt(len(g.orcs))
+	for _, orc := range g.orcs {
+		writeFloat(orc.positionX)
+		writeFloat(orc.positionY)
+		writeFloat(orc.knockbackX)
+		writeInt(orc.health)
+		writeInt(orc.shieldHits)
+		writeFloat(orc.blockTimer)
+		writeInt(int(orc.state))
+		writeInt(orc.currentFrame)
+		writeFloat(orc.attackCooldown)
+		writeInt(orc.attackCount)
+		writeFloat(orc.ai.RetreatTimer)
+		writeInt(boolInt(orc.ai.Aggro))
+		writeInt(boolInt(orc.ai.MovingRight))
+		writeInt(orc.bossPhase)
+		writeFloat(orc.specialTimer)
+		writeFloat(orc.telegraphTimer)
+	}
+
+	writeInt(len(g.projectiles))
+	for _, p := range g.projectiles {
+		writeFloat(p.X)
+		writeFloat(p.Y)
+		writeFloat(p.VX)
+		writeFloat(p.VY)
+		writeInt(int(p.Owner))
+	}
+
+	writeInt(len(g.pickups))
+	for _, p := range g.pickups {
+		writeFloat(p.X)
+		writeFloat(p.Depth)
+		writeFloat(p.Age)
+	}
+	writeInt(len(g.buffs.Active()))
+	for _, buff := range g.buffs.Active() {
+		writeInt(int(buff.Stat))
+		writeFloat(buff.Remaining)
+	}
+
+	h.Write([]byte(g.weaponName))
+	writeInt(g.shotSwing)
+	writeFloat(g.xp)
+	writeInt(g.playerLevel)
+	writeInt(g.pendingLevelUps)
+	writeInt(len(g.upgradeChoices))
+	writeInt(g.upgradeSelected)
+	for _, stat := range []float64{g.stats.Range, g.stats.Damage, g.stats.AttackSpeed, g.stats.MaxHealth, g.stats.Lifesteal, g.stats.Knockback, g.stats.MoveSpeed} {
+		writeFloat(stat)
+	}
+
+	return h.Sum64()
+}
+
+// boolInt converts a bool to 0 or 1 for hashing
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"time"
+)
+
+const (
+	scoresFileName = "scores.json"
+	scoresVersion  = 1
+
+	// maxHighScores is how many runs the score board keeps
+	maxHighScores = 10
+
+	// gameOverInputDelay is how many frames the game-over screen ignores input for
+	gameOverInputDelay = 60
+
+	// scoreChecksumKey signs the score file. It ships with the game, so the
+	// checksum catches hand edits and damaged files rather than stopping a
+	// determined cheat.
+	scoreChecksumKey = "orcslaughter high scores"
+)
+
+// RunRecord is one finished run on the score board
+type RunRecord struct {
+	Score        int       `json:"score"`
+	Kills        int       `json:"kills"`
+	SurvivalTime float64   `json:"survivalTime"` // Seconds from the first tick to the final blow
+	Wave         int       `json:"wave"`         // Wave the run ended on
+	Date         time.Time `json:"date"`
+	Mode         string    `json:"mode"` // Level played, the only choice that sets up a run differently
+	Seed         uint64    `json:"seed"` // Replay it with -seed
+}
+
+// ScoreBoard is the best runs so far, best first
+type ScoreBoard struct {
+	Runs []RunRecord
+}
+
+// scoreFile is how the score board is stored. The runs are kept as raw JSON so
+// the checksum is taken over exactly the bytes that were signed.
+type scoreFile struct {
+	Version  int             `json:"version"`
+	Runs     json.RawMessage `json:"runs"`
+	Checksum string          `json:"checksum"` // Hex HMAC-SHA256 of the compacted runs
+}
+
+// scoresPath returns the location of the score file in the user data directory
+func scoresPath() (string, error) {
+	dir, err := userDataDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find data directory: %w", err)
+	}
+	return filepath.Join(dir, "orcslaughter", scoresFileName), nil
+}
+
+// userDataDir returns the directory for user data. It's the config directory
+// everywhere but on Unix desktops, which keep data separately under XDG_DATA_HOME.
+func userDataDir() (string, error) {
+	switch runtime.GOOS {
+	case "windows", "darwin", "ios", "plan9", "android", "js", "wasip1":
+		return os.UserConfigDir()
+	}
+	if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share"), nil
+}
+
+// LoadScoreBoard reads the score file at path. It always returns a usable
+// board: a missing file yields an empty one, and a corrupt or tampered file is
+// set aside and replaced by an empty one.
+func LoadScoreBoard(path string) *ScoreBoard {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &ScoreBoard{}
+	}
+	if err != nil {
+		log.Printf("Failed to read high scores, starting a new table: %v", err)
+		return &ScoreBoard{}
+	}
+
+	board, err := parseScoreBoard(data)
+	if err != nil {
+		// Keep the bad file around for inspection instead of silently overwriting it
+		log.Printf("High score file is invalid, starting a new table: %v", err)
+		if err := os.Rename(path, path+".corrupt"); err != nil {
+			log.Printf("Failed to back up invalid high scores: %v", err)
+		}
+		return &ScoreBoard{}
+	}
+	return board
+}
+
+// parseScoreBoard decodes score file data, verifying its checksum
+func parseScoreBoard(data []byte) (*ScoreBoard, error) {
+	var file scoreFile
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, err
+	}
+	if file.Version != scoresVersion {
+		return nil, fmt.Errorf("unsupported high score version %d", file.Version)
+	}
+
+	// The file is indented, so compact the runs back to the form that was signed
+	var runs bytes.Buffer
+	if err := json.Compact(&runs, file.Runs); err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(file.Checksum), []byte(scoreChecksum(runs.Bytes()))) {
+		return nil, errors.New("checksum doesn't match, the file was edited or damaged")
+	}
+
+	board := &ScoreBoard{}
+	if err := json.Unmarshal(runs.Bytes(), &board.Runs); err != nil {
+		return nil, err
+	}
+	board.sort()
+	if len(board.Runs) > maxHighScores {
+		board.Runs = board.Runs[:maxHighScores]
+	}
+	return board, nil
+}
+
+// scoreChecksum signs compacted runs JSON
+func scoreChecksum(runs []byte) string {
+	mac := hmac.New(sha256.New, []byte(scoreChecksumKey))
+	mac.Write(runs)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Add puts a run on the board if it's good enough, returning its place from 0,
+// or -1 if it didn't make the cut
+func (b *ScoreBoard) Add(run RunRecord) int {
+	// Ties go to the earlier run, so a new run has to beat a score to take its place
+	place, _ := slices.BinarySearchFunc(b.Runs, run, func(a, run RunRecord) int {
+		if compareRuns(a, run) <= 0 {
+			return -1
+		}
+		return 1
+	})
+	if place >= maxHighScores {
+		return -1
+	}
+	b.Runs = slices.Insert(b.Runs, place, run)
+	if len(b.Runs) > maxHighScores {
+		b.Runs = b.Runs[:maxHighScores]
+	}
+	return place
+}
+
+// sort orders the runs best first
+func (b *ScoreBoard) sort() {
+	slices.SortStableFunc(b.Runs, compareRuns)
+}
+
+// compareRuns orders runs by score, then kills, then the longer survival
+func compareRuns(a, b RunRecord) int {
+	switch {
+	case a.Score != b.Score:
+		return b.Score - a.Score
+	case a.Kills != b.Kills:
+		return b.Kills - a.Kills
+	case a.SurvivalTime > b.SurvivalTime:
+		return -1
+	case a.SurvivalTime < b.SurvivalTime:
+		return 1
+	}
+	return 0
+}
+
+// Save writes the board to path, creating the directory if needed. The file is
+// replaced atomically, so a crash leaves either the old board or the new one.
+func (b *ScoreBoard) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create high score directory: %w", err)
+	}
+
+	runs, err := json.Marshal(b.Runs)
+	if err != nil {
+		return fmt.Errorf("failed to encode high scores: %w", err)
+	}
+	data, err := json.MarshalIndent(scoreFile{Version: scoresVersion, Runs: runs, Checksum: scoreChecksum(runs)}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode high scores: %w", err)
+	}
+
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write high scores: %w", err)
+	}
+	return nil
+}
+
+// recordRun puts the finished run on the score board and saves it. Replays
+// are runs that already happened, so they're never recorded.
+func (g *Game) recordRun() {
+	log.Printf("Game Over! Player died after killing %d orcs with a score of %d.", g.orcsKilled, g.score)
+	if g.replay != nil {
+		return
+	}
+	run := RunRecord{
+		Score:        g.score,
+		Kills:        g.orcsKilled,
+		SurvivalTime: float64(g.fightTicks) / 60,
+		Wave:         g.director.WaveNumber(),
+		Date:         time.Now().UTC().Truncate(time.Second),
+		Mode:         g.level.Name,
+		Seed:         g.seed,
+	}
+	g.lastRun = run
+	g.runPlace = g.scores.Add(run)
+
+	if g.runPlace < 0 || g.scoresPath == "" {
+		return
+	}
+	if err := g.scores.Save(g.scoresPath); err != nil {
+		log.Printf("Failed to save high scores: %v", err)
+	}
+}
+
+// formatDuration formats seconds as minutes and seconds
+func formatDuration(seconds float64) string {
+	total := int(seconds)
+	return fmt.Sprintf("%d:%02d", total/60, total%60)
+}
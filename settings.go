@@ -0,0 +1,361 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// settingsVersion is the current version of the settings file format.
+// Bump it and add an entry to settingsMigrations whenever the layout changes.
+const settingsVersion = 2
+
+// settingsFileName is the name of the settings file inside the config directory
+const settingsFileName = "settings.json"
+
+// Settings holds all user-configurable options that persist between sessions
+type Settings struct {
+	Version       int                   `json:"version"`
+	Audio         AudioSettings         `json:"audio"`
+	Window        WindowSettings        `json:"window"`
+	Input         InputSettings         `json:"input"`
+	Accessibility AccessibilitySettings `json:"accessibility"`
+}
+
+// AudioSettings holds volume levels in the range 0.0-1.0
+type AudioSettings struct {
+	MasterVolume  float64 `json:"masterVolume"`
+	MusicVolume   float64 `json:"musicVolume"`
+	EffectsVolume float64 `json:"effectsVolume"`
+}
+
+// WindowSettings holds the window size and mode
+type WindowSettings struct {
+	Width      int  `json:"width"`
+	Height     int  `json:"height"`
+	Fullscreen bool `json:"fullscreen"`
+}
+
+// InputSettings holds action bindings and input behavior options
+type InputSettings struct {
+	Bindings     map[Action][]Binding `json:"bindings"`
+	AttackMode   AttackMode           `json:"attackMode"`
+	AxisDeadzone float64              `json:"axisDeadzone"` // How far a stick must move to count as held
+}
+
+// AccessibilitySettings holds options that make the game more comfortable to play
+type AccessibilitySettings struct {
+	ReduceFlashing bool `json:"reduceFlashing"` // Keep sprites visible instead of blinking on death
+	HitStop        bool `json:"hitStop"`        // Freeze the action for a moment when hits land
+	ScreenShake    bool `json:"screenShake"`    // Shake the camera on hits
+	HitFlash       bool `json:"hitFlash"`       // Flash damaged fighters white
+	SlowMotion     bool `json:"slowMotion"`     // Slow down on the final kill of a wave
+}
+
+// Window size limits used when validating settings
+const (
+	minWindowWidth  = 320
+	minWindowHeight = 240
+	maxWindowWidth  = 7680
+	maxWindowHeight = 4320
+)
+
+// DefaultSettings returns the settings used when no settings file exists
+func DefaultSettings() *Settings {
+	return &Settings{
+		Version: settingsVersion,
+		Audio: AudioSettings{
+			MasterVolume:  1.0,
+			MusicVolume:   0.3, // Background music sits low in the mix
+			EffectsVolume: 0.5,
+		},
+		Window: WindowSettings{
+			Width:      screenWidth,
+			Height:     screenHeight,
+			Fullscreen: false,
+		},
+		Input: InputSettings{
+			Bindings:     defaultBindings(),
+			AttackMode:   AttackModePress,
+			AxisDeadzone: 0.5,
+		},
+		Accessibility: AccessibilitySettings{
+			ReduceFlashing: false,
+			HitStop:        true,
+			ScreenShake:    true,
+			HitFlash:       true,
+			SlowMotion:     true,
+		},
+	}
+}
+
+// defaultBindings returns the default keyboard and gamepad bindings for every action
+func defaultBindings() map[Action][]Binding {
+	return map[Action][]Binding{
+		ActionMoveLeft: {
+			KeyBinding(ebiten.KeyArrowLeft),
+			KeyBinding(ebiten.KeyA),
+			ButtonBinding(ebiten.StandardGamepadButtonLeftLeft),
+			AxisBinding(ebiten.StandardGamepadAxisLeftStickHorizontal, false),
+		},
+		ActionMoveRight: {
+			KeyBinding(ebiten.KeyArrowRight),
+			KeyBinding(ebiten.KeyD),
+			ButtonBinding(ebiten.StandardGamepadButtonLeftRight),
+			AxisBinding(ebiten.StandardGamepadAxisLeftStickHorizontal, true),
+		},
+		ActionAttack: {
+			KeyBinding(ebiten.KeySpace),
+			ButtonBinding(ebiten.StandardGamepadButtonRightLeft),
+		},
+		ActionDodge: {
+			KeyBinding(ebiten.KeyShiftLeft),
+			ButtonBinding(ebiten.StandardGamepadButtonRightBottom),
+		},
+		ActionPause: {
+			KeyBinding(ebiten.KeyEscape),
+			ButtonBinding(ebiten.StandardGamepadButtonCenterRight),
+		},
+		ActionBlock: {
+			KeyBinding(ebiten.KeyF),
+			ButtonBinding(ebiten.StandardGamepadButtonFrontTopLeft),
+		},
+		ActionMoveUp: {
+			KeyBinding(ebiten.KeyArrowUp),
+			KeyBinding(ebiten.KeyW),
+			ButtonBinding(ebiten.StandardGamepadButtonLeftTop),
+			AxisBinding(ebiten.StandardGamepadAxisLeftStickVertical, false),
+		},
+		ActionMoveDown: {
+			KeyBinding(ebiten.KeyArrowDown),
+			KeyBinding(ebiten.KeyS),
+			ButtonBinding(ebiten.StandardGamepadButtonLeftBottom),
+			AxisBinding(ebiten.StandardGamepadAxisLeftStickVertical, true),
+		},
+	}
+}
+
+// settingsMigrations upgrades raw settings data from version N to N+1, indexed by N
+var settingsMigrations = map[int]func(raw map[string]any){
+	// Version 0 files predate the version field; their layout matches version 1
+	0: func(raw map[string]any) {},
+
+	// Version 2 replaced the keyboard-only "keys" section with action bindings
+	// that also cover gamepads. Keys carry over; gamepad bindings get defaults.
+	1: func(raw map[string]any) {
+		bindings := map[string]any{}
+		if keys, ok := raw["keys"].(map[string]any); ok {
+			for action, list := range keys {
+				names, _ := list.([]any)
+				converted := make([]any, 0, len(names))
+				for _, name := range names {
+					if keyName, ok := name.(string); ok {
+						converted = append(converted, "Key:"+keyName)
+					}
+				}
+				bindings[action] = converted
+			}
+		}
+		delete(raw, "keys")
+
+		gamepadDefaults := map[string][]string{
+			"moveLeft":  {"Button:LeftLeft", "Axis:LeftStickHorizontal-"},
+			"moveRight": {"Button:LeftRight", "Axis:LeftStickHorizontal+"},
+			"attack":    {"Button:RightLeft"},
+		}
+		for action, defaults := range gamepadDefaults {
+			list, _ := bindings[action].([]any)
+			for _, binding := range defaults {
+				list = append(list, binding)
+			}
+			bindings[action] = list
+		}
+
+		raw["input"] = map[string]any{"bindings": bindings}
+	},
+}
+
+// settingsPath returns the location of the settings file in the user config directory
+func settingsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find config directory: %w", err)
+	}
+	return filepath.Join(dir, "orcslaughter", settingsFileName), nil
+}
+
+// LoadSettings reads the settings file at path, migrating and validating it.
+// It always returns usable settings: a missing or corrupt file yields the defaults.
+// The returned bool reports whether the file should be rewritten.
+func LoadSettings(path string) (*Settings, bool) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return DefaultSettings(), true
+	}
+	if err != nil {
+		log.Printf("Failed to read settings, using defaults: %v", err)
+		return DefaultSettings(), false
+	}
+
+	settings, migrated, err := parseSettings(data)
+	if err != nil {
+		// Keep the broken file around for inspection instead of silently overwriting it
+		log.Printf("Settings file is corrupt, using defaults: %v", err)
+		if err := os.Rename(path, path+".corrupt"); err != nil {
+			log.Printf("Failed to back up corrupt settings: %v", err)
+		}
+		return DefaultSettings(), true
+	}
+
+	changed := settings.Validate()
+	return settings, migrated || changed
+}
+
+// parseSettings decodes settings data, applying any migrations required
+func parseSettings(data []byte) (*Settings, bool, error) {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, false, err
+	}
+
+	version := 0
+	if v, ok := raw["version"].(float64); ok {
+		version = int(v)
+	}
+	if version > settingsVersion {
+		return nil, false, fmt.Errorf("unsupported settings version %d", version)
+	}
+
+	migrated := version != settingsVersion
+	for ; version < settingsVersion; version++ {
+		migrate, ok := settingsMigrations[version]
+		if !ok {
+			return nil, false, fmt.Errorf("no migration from settings version %d", version)
+		}
+		migrate(raw)
+	}
+	raw["version"] = settingsVersion
+
+	// Re-encode the migrated data and decode it on top of the defaults,
+	// so fields missing from the file keep their default values
+	migratedData, err := json.Marshal(raw)
+	if err != nil {
+		return nil, false, err
+	}
+	settings := DefaultSettings()
+	if err := json.Unmarshal(migratedData, settings); err != nil {
+		return nil, false, err
+	}
+
+	return settings, migrated, nil
+}
+
+// Validate clamps out-of-range values and restores missing bindings.
+// It returns true if anything had to be corrected.
+func (s *Settings) Validate() bool {
+	defaults := DefaultSettings()
+	changed := false
+
+	clampFloat := func(v *float64, min, max float64) {
+		if *v < min {
+			*v = min
+			changed = true
+		} else if *v > max {
+			*v = max
+			changed = true
+		}
+	}
+	clampInt := func(v *int, min, max int) {
+		if *v < min {
+			*v = min
+			changed = true
+		} else if *v > max {
+			*v = max
+			changed = true
+		}
+	}
+
+	clampFloat(&s.Audio.MasterVolume, 0, 1)
+	clampFloat(&s.Audio.MusicVolume, 0, 1)
+	clampFloat(&s.Audio.EffectsVolume, 0, 1)
+
+	clampInt(&s.Window.Width, minWindowWidth, maxWindowWidth)
+	clampInt(&s.Window.Height, minWindowHeight, maxWindowHeight)
+
+	if s.Input.AttackMode != AttackModePress && s.Input.AttackMode != AttackModeHold {
+		s.Input.AttackMode = defaults.Input.AttackMode
+		changed = true
+	}
+	clampFloat(&s.Input.AxisDeadzone, 0.1, 0.9)
+
+	// An action without any binding would make the game unplayable
+	if s.Input.Bindings == nil {
+		s.Input.Bindings = map[Action][]Binding{}
+	}
+	for a := Action(0); a < actionCount; a++ {
+		if len(s.Input.Bindings[a]) == 0 {
+			s.Input.Bindings[a] = defaults.Input.Bindings[a]
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// Save writes the settings to path, creating the directory if needed.
+// The file is replaced atomically so a crash never leaves it half-written.
+func (s *Settings) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create settings directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode settings: %w", err)
+	}
+
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write settings: %w", err)
+	}
+
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path, flushes it to
+// disk and renames it over path, so a crash leaves either the old file or the
+// new one, never half of one
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // Fails harmlessly once the rename has happened
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// EffectiveMusicVolume returns the effective volume for background music
+func (a AudioSettings) EffectiveMusicVolume() float64 {
+	return a.MasterVolume * a.MusicVolume
+}
+
+// EffectiveEffectsVolume returns the effective volume for a sound effect,
+// scaled by its mix level relative to the other effects
+func (a AudioSettings) EffectiveEffectsVolume(mix float64) float64 {
+	return a.MasterVolume * a.EffectsVolume * mix
+}
@@ -0,0 +1,462 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+)
+
+// WaveConfig is the wave data file: a fixed list of waves followed by
+// endless looping over the later waves with growing difficulty, plus the
+// milestones at which bosses appear
+type WaveConfig struct {
+	Waves   []WaveDefinition `json:"waves"`
+	Endless EndlessScaling   `json:"endless"`
+	Bosses  []BossMilestone  `json:"bosses"`
+}
+
+// WaveDefinition describes a single wave of enemies
+type WaveDefinition struct {
+	Name             string        `json:"name"`             // Optional title shown in the wave announcement
+	Intermission     float64       `json:"intermission"`     // Seconds of calm before the wave starts
+	Enemies          []WaveEnemy   `json:"enemies"`          // Enemy mix for the wave
+	SpawnPoints      []SpawnPoint  `json:"spawnPoints"`      // Where enemies enter, picked at random by weight
+	Interval         IntervalCurve `json:"interval"`         // Seconds between spawns over the course of the wave
+	SpeedMultiplier  float64       `json:"speedMultiplier"`  // Scales enemy walk speed
+	HealthMultiplier float64       `json:"healthMultiplier"` // Scales enemy health (rounded, minimum 1)
+}
+
+// WaveEnemy is one entry in a wave's enemy mix
+type WaveEnemy struct {
+	Type  string `json:"type"`
+	Count int    `json:"count"`
+}
+
+// SpawnPoint is a horizontal spawn position relative to the center of the view
+type SpawnPoint struct {
+	X      float64 `json:"x"`
+	Weight float64 `json:"weight"`
+}
+
+// IntervalCurve interpolates the spawn interval from Start to End as the wave progresses
+type IntervalCurve struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Curve string  `json:"curve"` // "linear", "easeIn" or "easeOut"
+}
+
+// EndlessScaling controls what happens after the last wave: waves from
+// RepeatFrom (1-based) onwards repeat, getting harder on every loop
+type EndlessScaling struct {
+	RepeatFrom    int     `json:"repeatFrom"`
+	CountPerLoop  float64 `json:"countPerLoop"`  // Extra fraction of enemies per loop
+	SpeedPerLoop  float64 `json:"speedPerLoop"`  // Added to the speed multiplier per loop
+	HealthPerLoop float64 `json:"healthPerLoop"` // Added to the health multiplier per loop
+}
+
+// BossMilestone spawns a boss every EveryKills kills or at the start of every
+// EveryWaves-th wave. Exactly one of the two must be set.
+type BossMilestone struct {
+	Type       string `json:"type"`
+	EveryKills int    `json:"everyKills"`
+	EveryWaves int    `json:"everyWaves"`
+}
+
+// wavesPath is the location of the wave data file
+const wavesPath = "assets/waves.json"
+
+// minSpawnInterval is the shortest allowed time between spawns
+const minSpawnInterval = 0.1
+
+// LoadWaveConfig reads and validates a wave data file.
+// isKnownType reports whether an enemy type name can be spawned.
+func LoadWaveConfig(path string, isKnownType func(string) bool) (*WaveConfig, error) {
+	config := &WaveConfig{}
+	validate := func() error { return config.Validate(isKnownType) }
+	if err := decodeDataFile(path, "wave", config, validate); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate checks that every wave can actually be spawned
+func (c *WaveConfig) Validate(isKnownType func(string) bool) error {
+	if len(c.Waves) == 0 {
+		return errors.New("no waves defined")
+	}
+
+	for i, wave := range c.Waves {
+		if len(wave.Enemies) == 0 {
+			return fmt.Errorf("wave %d: no enemies", i+1)
+		}
+		for _, enemy := range wave.Enemies {
+			if !isKnownType(enemy.Type) {
+				return fmt.Errorf("wave %d: unknown enemy type %q", i+1, enemy.Type)
+			}
+			if enemy.Count <= 0 {
+				return fmt.Errorf("wave %d: %s count must be positive", i+1, enemy.Type)
+			}
+		}
+
+		if len(wave.SpawnPoints) == 0 {
+			return fmt.Errorf("wave %d: no spawn points", i+1)
+		}
+		for _, point := range wave.SpawnPoints {
+			if point.Weight <= 0 {
+				return fmt.Errorf("wave %d: spawn point weights must be positive", i+1)
+			}
+		}
+
+		if wave.Interval.Start < minSpawnInterval || wave.Interval.End < minSpawnInterval {
+			return fmt.Errorf("wave %d: spawn interval must be at least %.1fs", i+1, minSpawnInterval)
+		}
+		switch wave.Interval.Curve {
+		case "", "linear", "easeIn", "easeOut":
+		default:
+			return fmt.Errorf("wave %d: unknown interval curve %q", i+1, wave.Interval.Curve)
+		}
+
+		if wave.SpeedMultiplier <= 0 || wave.HealthMultiplier <= 0 {
+			return fmt.Errorf("wave %d: speed and health multipliers must be positive", i+1)
+		}
+		if wave.Intermission < 0 {
+			return fmt.Errorf("wave %d: intermission cannot be negative", i+1)
+		}
+	}
+
+	if c.Endless.RepeatFrom < 1 || c.Endless.RepeatFrom > len(c.Waves) {
+		return fmt.Errorf("endless repeatFrom must be between 1 and %d", len(c.Waves))
+	}
+	if c.Endless.CountPerLoop < 0 || c.Endless.SpeedPerLoop < 0 || c.Endless.HealthPerLoop < 0 {
+		return errors.New("endless scaling cannot be negative")
+	}
+
+	for i, boss := range c.Bosses {
+		if !isKnownType(boss.Type) {
+			return fmt.Errorf("boss %d: unknown enemy type %q", i+1, boss.Type)
+		}
+		if boss.EveryKills < 0 || boss.EveryWaves < 0 || (boss.EveryKills > 0) == (boss.EveryWaves > 0) {
+			return fmt.Errorf("boss %d: set exactly one of everyKills and everyWaves to a positive value", i+1)
+		}
+	}
+
+	return nil
+}
+
+// intervalAt returns the spawn interval when the given fraction of the wave has spawned
+func (c IntervalCurve) intervalAt(progress float64) float64 {
+	switch c.Curve {
+	case "easeIn":
+		progress = progress * progress
+	case "easeOut":
+		progress = 1 - (1-progress)*(1-progress)
+	}
+	return c.Start + (c.End-c.Start)*progress
+}
+
+// SpawnRequest asks the game to spawn one enemy
+type SpawnRequest struct {
+	Type             string
+	X                float64
+	SpeedMultiplier  float64
+	HealthMultiplier float64
+}
+
+// directorPhase is where the director is in the wave cycle
+type directorPhase int
+
+const (
+	phaseIntermission directorPhase = iota // Waiting before the next wave
+	phaseSpawning                          // Spawning the wave's enemies
+	phaseClearing                          // Everything spawned, waiting for the player to finish them
+)
+
+// announcementDuration is how long wave announcements stay on screen
+const announcementDuration = 3.0
+
+// SpawnDirector decides when and where enemies spawn, wave by wave.
+// It doesn't touch the game directly, so it can be driven headlessly.
+type SpawnDirector struct {
+	config *WaveConfig
+	rng    *rand.Rand
+
+	waveNumber int            // 1-based number of the current (or upcoming) wave
+	wave       WaveDefinition // Current wave with endless scaling applied
+	phase      directorPhase
+	timer      float64  // Intermission countdown or time since the last spawn
+	queue      []string // Enemy types left to spawn this wave, in spawn order
+	spawned    int
+	total      int
+
+	nextBossKills []int    // Kill count at which each kill milestone's boss is due next
+	pendingBosses []string // Bosses due to spawn once no other boss is alive
+
+	announcement  string
+	announceTimer float64
+}
+
+// NewSpawnDirector creates a director that starts with the intermission before wave 1
+func NewSpawnDirector(config *WaveConfig, rng *rand.Rand) *SpawnDirector {
+	d := &SpawnDirector{config: config, rng: rng}
+	for _, boss := range config.Bosses {
+		d.nextBossKills = append(d.nextBossKills, boss.EveryKills)
+	}
+	d.prepareWave(1)
+	return d
+}
+
+// Reload swaps in a reloaded wave config. The current wave carries on as it
+// was and the new waves take effect from the next one. Kill milestones are
+// counted afresh from kills, the number killed so far, so added or reordered
+// milestones are due at their next multiple.
+func (d *SpawnDirector) Reload(config *WaveConfig, kills int) {
+	// Queue any boss already due under the old milestones before they're replaced
+	d.checkKillMilestones(kills)
+
+	*d.config = *config
+	d.nextBossKills = d.nextBossKills[:0]
+	for _, boss := range config.Bosses {
+		next := 0
+		if boss.EveryKills > 0 {
+			next = (kills/boss.EveryKills + 1) * boss.EveryKills
+		}
+		d.nextBossKills = append(d.nextBossKills, next)
+	}
+}
+
+// prepareWave sets up the given wave and starts its intermission
+func (d *SpawnDirector) prepareWave(number int) {
+	d.waveNumber = number
+	d.wave = d.resolveWave(number)
+	d.phase = phaseIntermission
+	d.timer = d.wave.Intermission
+	d.spawned = 0
+
+	// Build and shuffle the spawn queue so the enemy mix arrives in random order
+	d.queue = d.queue[:0]
+	for _, enemy := range d.wave.Enemies {
+		for i := 0; i < enemy.Count; i++ {
+			d.queue = append(d.queue, enemy.Type)
+		}
+	}
+	d.rng.Shuffle(len(d.queue), func(i, j int) {
+		d.queue[i], d.queue[j] = d.queue[j], d.queue[i]
+	})
+	d.total = len(d.queue)
+}
+
+// resolveWave returns the definition for a wave number, applying endless scaling
+// once the fixed waves run out
+func (d *SpawnDirector) resolveWave(number int) WaveDefinition {
+	waves := d.config.Waves
+	if number <= len(waves) {
+		return waves[number-1]
+	}
+
+	endless := d.config.Endless
+	loopLength := len(waves) - endless.RepeatFrom + 1
+	past := number - len(waves) - 1
+	loop := past/loopLength + 1
+
+	wave := waves[endless.RepeatFrom-1+past%loopLength]
+	wave.Name = ""
+	wave.SpeedMultiplier += endless.SpeedPerLoop * float64(loop)
+	wave.HealthMultiplier += endless.HealthPerLoop * float64(loop)
+
+	countScale := 1 + endless.CountPerLoop*float64(loop)
+	enemies := make([]WaveEnemy, len(wave.Enemies))
+	for i, enemy := range wave.Enemies {
+		enemies[i] = WaveEnemy{Type: enemy.Type, Count: int(math.Ceil(float64(enemy.Count) * countScale))}
+	}
+	wave.Enemies = enemies
+
+	return wave
+}
+
+// Update advances the director by one tick. alive is the number of living enemies,
+// kills the number killed so far and bossAlive whether a boss is on the field.
+// It returns the enemies to spawn this tick, usually none.
+func (d *SpawnDirector) Update(alive, kills int, bossAlive bool) []SpawnRequest {
+	const dt = 1.0 / 60.0 // Assuming 60 FPS
+
+	if d.announceTimer > 0 {
+		d.announceTimer -= dt
+	}
+
+	d.checkKillMilestones(kills)
+	if !bossAlive && len(d.pendingBosses) > 0 {
+		// Bosses arrive one at a time
+		return []SpawnRequest{d.spawnBoss()}
+	}
+
+	switch d.phase {
+	case phaseIntermission:
+		// Like the spawning phase, the countdown waits for the boss to fall,
+		// so the next wave can't march in on a boss fight
+		if bossAlive {
+			break
+		}
+		d.timer -= dt
+		if d.timer <= 0 {
+			d.phase = phaseSpawning
+			d.timer = 0
+			d.Announce(d.waveTitle())
+			d.checkWaveMilestones()
+			// The first enemy arrives as soon as the wave starts
+			return d.spawnNext()
+		}
+
+	case phaseSpawning:
+		// Hold the rest of the wave back while a boss is fighting
+		if bossAlive {
+			break
+		}
+		d.timer += dt
+		if d.timer >= d.currentInterval() {
+			d.timer = 0
+			return d.spawnNext()
+		}
+
+	case phaseClearing:
+		if alive == 0 {
+			d.Announce(fmt.Sprintf("Wave %d cleared!", d.waveNumber))
+			d.prepareWave(d.waveNumber + 1)
+		}
+	}
+
+	return nil
+}
+
+// checkKillMilestones queues the bosses whose kill milestone has been reached
+func (d *SpawnDirector) checkKillMilestones(kills int) {
+	for i, boss := range d.config.Bosses {
+		if boss.EveryKills <= 0 || i >= len(d.nextBossKills) {
+			continue
+		}
+		if kills >= d.nextBossKills[i] {
+			d.pendingBosses = append(d.pendingBosses, boss.Type)
+			// Skip milestones passed in the meantime rather than queueing a pile of bosses
+			for d.nextBossKills[i] <= kills {
+				d.nextBossKills[i] += boss.EveryKills
+			}
+		}
+	}
+}
+
+// checkWaveMilestones queues the bosses due at the start of the current wave
+func (d *SpawnDirector) checkWaveMilestones() {
+	for _, boss := range d.config.Bosses {
+		if boss.EveryWaves > 0 && d.waveNumber%boss.EveryWaves == 0 {
+			d.pendingBosses = append(d.pendingBosses, boss.Type)
+		}
+	}
+}
+
+// spawnBoss pops the next pending boss
+func (d *SpawnDirector) spawnBoss() SpawnRequest {
+	bossType := d.pendingBosses[0]
+	d.pendingBosses = d.pendingBosses[1:]
+
+	return SpawnRequest{
+		Type:             bossType,
+		X:                d.pickSpawnPoint().X,
+		SpeedMultiplier:  d.wave.SpeedMultiplier,
+		HealthMultiplier: d.wave.HealthMultiplier,
+	}
+}
+
+// spawnNext pops the next enemy off the queue and picks its spawn point
+func (d *SpawnDirector) spawnNext() []SpawnRequest {
+	enemyType := d.queue[0]
+	d.queue = d.queue[1:]
+	d.spawned++
+	if len(d.queue) == 0 {
+		d.phase = phaseClearing
+	}
+
+	return []SpawnRequest{{
+		Type:             enemyType,
+		X:                d.pickSpawnPoint().X,
+		SpeedMultiplier:  d.wave.SpeedMultiplier,
+		HealthMultiplier: d.wave.HealthMultiplier,
+	}}
+}
+
+// pickSpawnPoint chooses a spawn point at random, weighted by each point's weight
+func (d *SpawnDirector) pickSpawnPoint() SpawnPoint {
+	points := d.wave.SpawnPoints
+	totalWeight := 0.0
+	for _, point := range points {
+		totalWeight += point.Weight
+	}
+
+	roll := d.rng.Float64() * totalWeight
+	for _, point := range points {
+		roll -= point.Weight
+		if roll < 0 {
+			return point
+		}
+	}
+	return points[len(points)-1]
+}
+
+// currentInterval returns the time until the next spawn based on wave progress
+func (d *SpawnDirector) currentInterval() float64 {
+	progress := 0.0
+	if d.total > 1 {
+		progress = float64(d.spawned-1) / float64(d.total-1)
+	}
+	return math.Max(minSpawnInterval, d.wave.Interval.intervalAt(progress))
+}
+
+// waveTitle returns the announcement shown when a wave starts
+func (d *SpawnDirector) waveTitle() string {
+	if d.wave.Name != "" {
+		return fmt.Sprintf("Wave %d: %s", d.waveNumber, d.wave.Name)
+	}
+	return fmt.Sprintf("Wave %d", d.waveNumber)
+}
+
+// Announce shows a message in the HUD for a few seconds
+func (d *SpawnDirector) Announce(message string) {
+	d.announcement = message
+	d.announceTimer = announcementDuration
+}
+
+// WaveNumber returns the current wave number (1-based)
+func (d *SpawnDirector) WaveNumber() int {
+	return d.waveNumber
+}
+
+// Announcement returns the message to show in the HUD, or "" if there is none
+func (d *SpawnDirector) Announcement() string {
+	if d.announceTimer <= 0 {
+		return ""
+	}
+	return d.announcement
+}
+
+// WaveFullySpawned reports whether every enemy of the current wave has arrived,
+// so the wave ends once the living ones are dead
+func (d *SpawnDirector) WaveFullySpawned() bool {
+	return d.phase == phaseClearing
+}
+
+// InIntermission reports whether the director is waiting between waves
+func (d *SpawnDirector) InIntermission() bool {
+	return d.phase == phaseIntermission
+}
+
+// IntermissionRemaining returns the seconds left before the next wave starts
+func (d *SpawnDirector) IntermissionRemaining() float64 {
+	if d.phase != phaseIntermission {
+		return 0
+	}
+	return d.timer
+}
+
+// IntermissionDuration returns the full length of the current wave's intermission
+func (d *SpawnDirector) IntermissionDuration() float64 {
+	return d.wave.Intermission
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// tuningPath is the location of the balance data file
+const tuningPath = "assets/tuning.json"
+
+// Tuning holds every gameplay balance value, loaded from tuningPath.
+// Systems keep a pointer to the shared Tuning so a reload takes effect immediately.
+type Tuning struct {
+	Player PlayerTuning `json:"player"`
+	Enemy  EnemyTuning  `json:"enemy"`
+	Arena  ArenaTuning  `json:"arena"`
+	Camera CameraTuning `json:"camera"`
+	Juice  JuiceTuning  `json:"juice"`
+	Text   TextTuning   `json:"text"`
+}
+
+// TextTuning holds the values for floating damage numbers, the kill feed and kill streaks
+type TextTuning struct {
+	NumberDuration float64 `json:"numberDuration"` // Seconds a damage number stays up
+	NumberRise     float64 `json:"numberRise"`     // Pixels a damage number floats up over its life
+	NumberScale    float64 `json:"numberScale"`    // Size multiplier for damage numbers, on top of the numbers font size
+	CritScale      float64 `json:"critScale"`      // Size multiplier for critical hits, on top of the numbers font size
+
+	FeedLines    int     `json:"feedLines"`    // Kills listed in the feed at once
+	FeedDuration float64 `json:"feedDuration"` // Seconds a kill stays in the feed
+
+	StreakWindow   float64        `json:"streakWindow"`   // Seconds after a kill within which the next one extends the streak
+	StreakDuration float64        `json:"streakDuration"` // Seconds a streak announcement stays up
+	Streaks        []StreakTuning `json:"streaks"`        // Announcements, in ascending order of kills
+}
+
+// StreakTuning is a kill streak announcement, made when a streak reaches Kills kills
+type StreakTuning struct {
+	Kills int    `json:"kills"`
+	Name  string `json:"name"`
+}
+
+// CameraTuning holds the values for how the camera follows the player
+type CameraTuning struct {
+	Deadzone  [2]float64 `json:"deadzone"`  // Half-size in screen pixels of the box around the center the player can move in without the camera following
+	Smoothing float64    `json:"smoothing"` // Fraction of the distance to its target the camera covers per tick; 1 snaps
+	Zoom      float64    `json:"zoom"`      // Normal zoom; 1 shows the world at its real size
+	BossZoom  float64    `json:"bossZoom"`  // Zoom during boss fights
+	ZoomSpeed float64    `json:"zoomSpeed"` // Fraction of the way to a new zoom covered per tick
+
+	// Shake is driven by trauma from 0 to 1, which hits add to and which wears off over time
+	MaxShake    float64 `json:"maxShake"`    // Pixels of shake at full trauma
+	TraumaDecay float64 `json:"traumaDecay"` // Trauma lost per second
+}
+
+// JuiceTuning holds the strength of the feedback effects that make hits feel heavy.
+// Each effect can be turned off in the settings.
+type JuiceTuning struct {
+	// Hit-stop: seconds the action freezes on impact
+	HitStop      float64 `json:"hitStop"`      // The player lands a hit
+	KillHitStop  float64 `json:"killHitStop"`  // The player lands a killing blow
+	HurtHitStop  float64 `json:"hurtHitStop"`  // The player is hit
+	ParryHitStop float64 `json:"parryHitStop"` // The player parries
+
+	// Trauma added to the camera, from 0 to 1
+	HitTrauma     float64 `json:"hitTrauma"`
+	KillTrauma    float64 `json:"killTrauma"`
+	HurtTrauma    float64 `json:"hurtTrauma"`
+	BlockTrauma   float64 `json:"blockTrauma"`
+	ParryTrauma   float64 `json:"parryTrauma"`
+	SpecialTrauma float64 `json:"specialTrauma"` // A boss's special attack lands on the player
+
+	FlashDuration float64 `json:"flashDuration"` // Seconds a damaged fighter flashes white
+
+	// Slow motion on the final kill of a wave
+	SlowMotionScale    float64 `json:"slowMotionScale"`    // Game speed during slow motion, above 0 and up to 1
+	SlowMotionDuration float64 `json:"slowMotionDuration"` // Seconds of real time it lasts
+}
+
+// ArenaTuning describes the floor fighters walk on. Like other positions,
+// depths are in pixels below the screen center; larger is closer to the camera.
+type ArenaTuning struct {
+	DepthMin float64 `json:"depthMin"` // Furthest back a fighter can stand
+	DepthMax float64 `json:"depthMax"` // Furthest forward a fighter can stand
+	HitDepth float64 `json:"hitDepth"` // How far apart in depth two fighters can be and still hit each other
+}
+
+// ClampDepth keeps a depth on the arena floor
+func (a *ArenaTuning) ClampDepth(y float64) float64 {
+	return max(a.DepthMin, min(a.DepthMax, y))
+}
+
+// PlayerTuning holds balance values for the soldier
+type PlayerTuning struct {
+	MaxHealth     float64 `json:"maxHealth"`
+	WalkSpeed     float64 `json:"walkSpeed"`     // Pixels per tick
+	DepthSpeed    float64 `json:"depthSpeed"`    // Pixels per tick when walking up or down the arena
+	FrameDuration float64 `json:"frameDuration"` // Seconds per animation frame
+	BodySize      float64 `json:"bodySize"`      // Collision box size in sprite pixels
+	DeathDelay    float64 `json:"deathDelay"`    // Seconds before the death flashing starts
+	FlashCount    int     `json:"flashCount"`    // Number of flashes before game over
+	FlashInterval float64 `json:"flashInterval"` // Seconds between flash toggles
+
+	HitInvulnerability float64       `json:"hitInvulnerability"` // Seconds the player can't be hit again after taking a hit
+	Dodge              DodgeTuning   `json:"dodge"`
+	Stamina            StaminaTuning `json:"stamina"`
+	Block              BlockTuning   `json:"block"`
+	Deflect            DeflectTuning `json:"deflect"`
+
+	// Attack combo: pressing attack during a swing, or within ComboWindow after it,
+	// chains into the next step. The steps are the wielded weapon's, see WeaponDefinition.
+	ComboWindow  float64 `json:"comboWindow"`  // Seconds after a swing in which the next press continues the combo
+	ComboTimeout float64 `json:"comboTimeout"` // Seconds without landing a hit before the HUD hit counter resets
+}
+
+// DodgeTuning holds the values for the player's dodge roll
+type DodgeTuning struct {
+	Tag          string     `json:"tag"`          // Animation tag in Soldier.aseprite
+	Speed        float64    `json:"speed"`        // Pixels per tick while rolling
+	Duration     float64    `json:"duration"`     // Seconds the roll lasts
+	Invulnerable [2]float64 `json:"invulnerable"` // Start and end of the invulnerability window, in seconds into the roll
+	Cooldown     float64    `json:"cooldown"`     // Seconds after a roll before the next one
+	StaminaCost  float64    `json:"staminaCost"`
+}
+
+// StaminaTuning holds the values for the stamina that dodging costs
+type StaminaTuning struct {
+	Max            float64 `json:"max"`
+	RegenPerSecond float64 `json:"regenPerSecond"`
+	RegenDelay     float64 `json:"regenDelay"` // Seconds after spending stamina before it starts coming back
+}
+
+// BlockTuning holds the values for blocking and parrying
+type BlockTuning struct {
+	Tag                 string  `json:"tag"`                 // Pose in Soldier.aseprite, its first frame is held
+	DamageMultiplier    float64 `json:"damageMultiplier"`    // Share of a blocked frontal hit's damage still taken
+	KnockbackMultiplier float64 `json:"knockbackMultiplier"` // Share of a blocked frontal hit's knockback still taken
+	ParryWindow         float64 `json:"parryWindow"`         // Seconds after raising the block during which a hit is parried
+	ParryCooldown       float64 `json:"parryCooldown"`       // Seconds after lowering the block before raising it again can parry
+	StunDuration        float64 `json:"stunDuration"`        // Seconds a parried attacker is stunned
+	CritWindow          float64 `json:"critWindow"`          // Seconds after a parry during which the player's hits are critical
+	CritMultiplier      float64 `json:"critMultiplier"`      // Damage multiplier of critical hits
+}
+
+// DeflectTuning holds how hard a projectile batted back by the player hits an enemy
+type DeflectTuning struct {
+	Damage    int     `json:"damage"` // Health taken from the enemy it hits
+	Knockback float64 `json:"knockback"`
+}
+
+// EnemyTuning holds balance values shared by every enemy type.
+// Per-type values live in the enemy definition file.
+type EnemyTuning struct {
+	FrameDuration     float64 `json:"frameDuration"`     // Seconds per animation frame
+	KnockbackFriction float64 `json:"knockbackFriction"` // Fraction of knockback velocity kept each tick
+	KnockdownDuration float64 `json:"knockdownDuration"` // Seconds an enemy stays down after a finisher
+	DeathDelay        float64 `json:"deathDelay"`        // Seconds before the death flashing starts
+	FlashCount        int     `json:"flashCount"`        // Number of flashes before removal
+	FlashInterval     float64 `json:"flashInterval"`     // Seconds between flash toggles
+}
+
+// LoadTuning reads and validates a tuning file
+func LoadTuning(path string) (*Tuning, error) {
+	tuning := &Tuning{}
+	if err := decodeDataFile(path, "tuning", tuning, tuning.Validate); err != nil {
+		return nil, err
+	}
+	return tuning, nil
+}
+
+// decodeDataFile reads the JSON data file at path into v and checks it with
+// validate. what names the kind of file in errors, such as "tuning".
+func decodeDataFile(path, what string, v any, validate func() error) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s file: %w", what, err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields() // Catch typos in field names instead of silently ignoring them
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("failed to parse %s file: %w", what, err)
+	}
+	if err := validate(); err != nil {
+		return fmt.Errorf("invalid %s file: %w", what, err)
+	}
+
+	return nil
+}
+
+// Validate checks that every value is in a sensible range
+func (t *Tuning) Validate() error {
+	var errs []error
+	positive := func(name string, v float64) {
+		if v <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive, got %v", name, v))
+		}
+	}
+	nonNegative := func(name string, v float64) {
+		if v < 0 {
+			errs = append(errs, fmt.Errorf("%s cannot be negative, got %v", name, v))
+		}
+	}
+
+	p := t.Player
+	positive("player.maxHealth", p.MaxHealth)
+	positive("player.walkSpeed", p.WalkSpeed)
+	positive("player.depthSpeed", p.DepthSpeed)
+	positive("player.frameDuration", p.FrameDuration)
+	positive("player.bodySize", p.BodySize)
+	nonNegative("player.deathDelay", p.DeathDelay)
+	nonNegative("player.flashCount", float64(p.FlashCount))
+	positive("player.flashInterval", p.FlashInterval)
+	nonNegative("player.hitInvulnerability", p.HitInvulnerability)
+	if p.Dodge.Tag == "" {
+		errs = append(errs, errors.New("player.dodge.tag is required"))
+	}
+	positive("player.dodge.speed", p.Dodge.Speed)
+	positive("player.dodge.duration", p.Dodge.Duration)
+	if w := p.Dodge.Invulnerable; w[0] < 0 || w[1] < w[0] || w[1] > p.Dodge.Duration {
+		errs = append(errs, fmt.Errorf("player.dodge.invulnerable must be an ascending window within the roll, got %v", w))
+	}
+	nonNegative("player.dodge.cooldown", p.Dodge.Cooldown)
+	nonNegative("player.dodge.staminaCost", p.Dodge.StaminaCost)
+	positive("player.stamina.max", p.Stamina.Max)
+	nonNegative("player.stamina.regenPerSecond", p.Stamina.RegenPerSecond)
+	nonNegative("player.stamina.regenDelay", p.Stamina.RegenDelay)
+	if p.Dodge.StaminaCost > p.Stamina.Max {
+		errs = append(errs, fmt.Errorf("player.dodge.staminaCost (%v) is more than player.stamina.max (%v)", p.Dodge.StaminaCost, p.Stamina.Max))
+	}
+	if p.Block.Tag == "" {
+		errs = append(errs, errors.New("player.block.tag is required"))
+	}
+	if m := p.Block.DamageMultiplier; m < 0 || m > 1 {
+		errs = append(errs, fmt.Errorf("player.block.damageMultiplier must be between 0 and 1, got %v", m))
+	}
+	if m := p.Block.KnockbackMultiplier; m < 0 || m > 1 {
+		errs = append(errs, fmt.Errorf("player.block.knockbackMultiplier must be between 0 and 1, got %v", m))
+	}
+	nonNegative("player.block.parryWindow", p.Block.ParryWindow)
+	nonNegative("player.block.parryCooldown", p.Block.ParryCooldown)
+	nonNegative("player.block.stunDuration", p.Block.StunDuration)
+	nonNegative("player.block.critWindow", p.Block.CritWindow)
+	if p.Block.CritMultiplier < 1 {
+		errs = append(errs, fmt.Errorf("player.block.critMultiplier must be at least 1, got %v", p.Block.CritMultiplier))
+	}
+	positive("player.deflect.damage", float64(p.Deflect.Damage))
+	nonNegative("player.deflect.knockback", p.Deflect.Knockback)
+	nonNegative("player.comboWindow", p.ComboWindow)
+	positive("player.comboTimeout", p.ComboTimeout)
+
+	e := t.Enemy
+	positive("enemy.frameDuration", e.FrameDuration)
+	if e.KnockbackFriction < 0 || e.KnockbackFriction >= 1 {
+		errs = append(errs, fmt.Errorf("enemy.knockbackFriction must be in [0, 1), got %v", e.KnockbackFriction))
+	}
+	nonNegative("enemy.deathDelay", e.DeathDelay)
+	nonNegative("enemy.flashCount", float64(e.FlashCount))
+	positive("enemy.flashInterval", e.FlashInterval)
+	nonNegative("enemy.knockdownDuration", e.KnockdownDuration)
+
+	a := t.Arena
+	if a.DepthMax < a.DepthMin {
+		errs = append(errs, fmt.Errorf("arena.depthMax (%v) is less than arena.depthMin (%v)", a.DepthMax, a.DepthMin))
+	}
+	if a.DepthMin < -screenHeight/2 || a.DepthMax > screenHeight/2 {
+		errs = append(errs, fmt.Errorf("arena depths must be on screen, within ±%d", screenHeight/2))
+	}
+	positive("arena.hitDepth", a.HitDepth)
+
+	c := t.Camera
+	nonNegative("camera.deadzone[0]", c.Deadzone[0])
+	nonNegative("camera.deadzone[1]", c.Deadzone[1])
+	if c.Smoothing <= 0 || c.Smoothing > 1 {
+		errs = append(errs, fmt.Errorf("camera.smoothing must be in (0, 1], got %v", c.Smoothing))
+	}
+	positive("camera.zoom", c.Zoom)
+	positive("camera.bossZoom", c.BossZoom)
+	if c.ZoomSpeed <= 0 || c.ZoomSpeed > 1 {
+		errs = append(errs, fmt.Errorf("camera.zoomSpeed must be in (0, 1], got %v", c.ZoomSpeed))
+	}
+	nonNegative("camera.maxShake", c.MaxShake)
+	positive("camera.traumaDecay", c.TraumaDecay)
+
+	j := t.Juice
+	nonNegative("juice.hitStop", j.HitStop)
+	nonNegative("juice.killHitStop", j.KillHitStop)
+	nonNegative("juice.hurtHitStop", j.HurtHitStop)
+	nonNegative("juice.parryHitStop", j.ParryHitStop)
+	trauma := func(name string, v float64) {
+		if v < 0 || v > 1 {
+			errs = append(errs, fmt.Errorf("%s must be between 0 and 1, got %v", name, v))
+		}
+	}
+	trauma("juice.hitTrauma", j.HitTrauma)
+	trauma("juice.killTrauma", j.KillTrauma)
+	trauma("juice.hurtTrauma", j.HurtTrauma)
+	trauma("juice.blockTrauma", j.BlockTrauma)
+	trauma("juice.parryTrauma", j.ParryTrauma)
+	trauma("juice.specialTrauma", j.SpecialTrauma)
+	nonNegative("juice.flashDuration", j.FlashDuration)
+	if s := j.SlowMotionScale; s <= 0 || s > 1 {
+		errs = append(errs, fmt.Errorf("juice.slowMotionScale must be in (0, 1], got %v", s))
+	}
+	nonNegative("juice.slowMotionDuration", j.SlowMotionDuration)
+
+	txt := t.Text
+	positive("text.numberDuration", txt.NumberDuration)
+	nonNegative("text.numberRise", txt.NumberRise)
+	positive("text.numberScale", txt.NumberScale)
+	positive("text.critScale", txt.CritScale)
+	nonNegative("text.feedLines", float64(txt.FeedLines))
+	positive("text.feedDuration", txt.FeedDuration)
+	positive("text.streakWindow", txt.StreakWindow)
+	positive("text.streakDuration", txt.StreakDuration)
+	for i, streak := range txt.Streaks {
+		name := fmt.Sprintf("text.streaks[%d]", i)
+		if streak.Name == "" {
+			errs = append(errs, fmt.Errorf("%s.name is required", name))
+		}
+		if streak.Kills < 2 {
+			errs = append(errs, fmt.Errorf("%s.kills must be at least 2, got %d", name, streak.Kills))
+		}
+		if i > 0 && streak.Kills <= txt.Streaks[i-1].Kills {
+			errs = append(errs, fmt.Errorf("%s.kills must be more than the streak before it", name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// CheckTags checks that every animation tag the tuning names is in the
+// player's sprite, given a function that reports whether it has a tag
+func (t *Tuning) CheckTags(hasTag func(string) bool) error {
+	var errs []error
+	for _, tag := range []struct{ field, name string }{
+		{"player.dodge.tag", t.Player.Dodge.Tag},
+		{"player.block.tag", t.Player.Block.Tag},
+	} {
+		if !hasTag(tag.name) {
+			errs = append(errs, fmt.Errorf("%s %q is not a tag in Soldier.aseprite", tag.field, tag.name))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,187 @@
+//go:build dev
+
+package main
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// dataReloadInterval is how often dev builds check data files for changes
+const dataReloadInterval = 60 // ticks
+
+// dataWatcher reloads tuning, enemy, wave, level, particle, pickup, upgrade and weapon data when the files change on disk,
+// so balance can be adjusted while the game is running.
+// Only compiled into dev builds (go build -tags dev).
+type dataWatcher struct {
+	ticks         int
+	tuningTime    time.Time
+	enemiesTime   time.Time
+	wavesTime     time.Time
+	levelsTime    time.Time
+	particlesTime time.Time
+	pickupsTime   time.Time
+	upgradesTime  time.Time
+	weaponsTime   time.Time
+	initialized   bool
+}
+
+// watchDataFiles checks the data files for changes and reloads them in place.
+// Invalid files are reported and ignored, keeping the last good values.
+func (g *Game) watchDataFiles() {
+	w := &g.dataWatcher
+	w.ticks++
+	if w.ticks < dataReloadInterval {
+		return
+	}
+	w.ticks = 0
+
+	tuningTime := modTime(tuningPath)
+	enemiesTime := modTime(enemiesPath)
+	wavesTime := modTime(wavesPath)
+	levelsTime := modTime(levelsPath)
+	particlesTime := modTime(particlesPath)
+	pickupsTime := modTime(pickupsPath)
+	upgradesTime := modTime(upgradesPath)
+	weaponsTime := modTime(weaponsPath)
+	if !w.initialized {
+		w.tuningTime, w.enemiesTime, w.wavesTime, w.levelsTime = tuningTime, enemiesTime, wavesTime, levelsTime
+		w.particlesTime, w.pickupsTime, w.upgradesTime, w.weaponsTime = particlesTime, pickupsTime, upgradesTime, weaponsTime
+		w.initialized = true
+		return
+	}
+
+	if !tuningTime.Equal(w.tuningTime) {
+		w.tuningTime = tuningTime
+		tuning, err := LoadTuning(tuningPath)
+		if err == nil {
+			err = tuning.CheckTags(g.hasPlayerTag)
+		}
+		if err != nil {
+			log.Printf("Tuning reload failed, keeping previous values: %v", err)
+		} else {
+			*g.tuning = *tuning
+			log.Printf("Reloaded %s", tuningPath)
+		}
+	}
+
+	if !enemiesTime.Equal(w.enemiesTime) {
+		w.enemiesTime = enemiesTime
+		enemies, err := LoadEnemyDefinitions(enemiesPath)
+		if err != nil {
+			log.Printf("Enemy reload failed, keeping previous definitions: %v", err)
+		} else {
+			g.enemies.Reload(enemies)
+			log.Printf("Reloaded %s", enemiesPath)
+		}
+	}
+
+	if !wavesTime.Equal(w.wavesTime) {
+		w.wavesTime = wavesTime
+		waves, err := LoadWaveConfig(wavesPath, g.enemies.Has)
+		if err != nil {
+			log.Printf("Wave reload failed, keeping previous waves: %v", err)
+		} else {
+			g.director.Reload(waves, g.orcsKilled)
+			log.Printf("Reloaded %s (takes effect from the next wave)", wavesPath)
+		}
+	}
+
+	if !levelsTime.Equal(w.levelsTime) {
+		w.levelsTime = levelsTime
+		levels, err := LoadLevels(levelsPath)
+		if err == nil {
+			var level *Level
+			level, err = levels.Find(g.level.Name)
+			if err == nil {
+				var background *Background
+				background, err = LoadBackground(level.Background)
+				if err == nil {
+					*g.level = *level
+					g.background = background
+				}
+			}
+		}
+		if err != nil {
+			log.Printf("Level reload failed, keeping the current level: %v", err)
+		} else {
+			log.Printf("Reloaded %s", levelsPath)
+		}
+	}
+
+	if !particlesTime.Equal(w.particlesTime) {
+		w.particlesTime = particlesTime
+		particles, err := LoadParticleConfig(particlesPath)
+		if err == nil {
+			err = g.particles.Reload(particles)
+		}
+		if err != nil {
+			log.Printf("Particle reload failed, keeping previous presets: %v", err)
+		} else {
+			log.Printf("Reloaded %s", particlesPath)
+		}
+	}
+
+	// Weapons reload ahead of pickups, which check the weapons they equip. A
+	// removed weapon in hand falls back to the default.
+	if !weaponsTime.Equal(w.weaponsTime) {
+		w.weaponsTime = weaponsTime
+		weapons, err := LoadWeaponConfig(weaponsPath)
+		var icons map[string]*ebiten.Image
+		if err == nil {
+			err = weapons.CheckSprite(g.hasPlayerTag, g.hasPlayerLayer)
+		}
+		if err == nil {
+			icons, err = LoadPickupIcons(g.pickupConfig, weapons)
+		}
+		if err != nil {
+			log.Printf("Weapon reload failed, keeping previous weapons: %v", err)
+		} else {
+			*g.weapons = *weapons
+			g.pickupIcons = icons
+			g.preloadWeaponSounds()
+			g.refreshPlayerSprite()
+			log.Printf("Reloaded %s", weaponsPath)
+		}
+	}
+
+	if !pickupsTime.Equal(w.pickupsTime) {
+		w.pickupsTime = pickupsTime
+		pickups, err := LoadPickupConfig(pickupsPath, g.enemies.Has, g.weapons.Has)
+		var icons map[string]*ebiten.Image
+		if err == nil {
+			icons, err = LoadPickupIcons(pickups, g.weapons)
+		}
+		if err != nil {
+			log.Printf("Pickup reload failed, keeping previous pickups: %v", err)
+		} else {
+			*g.pickupConfig = *pickups
+			g.pickupIcons = icons
+			log.Printf("Reloaded %s", pickupsPath)
+		}
+	}
+
+	// Stats already gained are kept; new values apply to the next level-up
+	if !upgradesTime.Equal(w.upgradesTime) {
+		w.upgradesTime = upgradesTime
+		upgrades, err := LoadUpgradeConfig(upgradesPath)
+		if err != nil {
+			log.Printf("Upgrade reload failed, keeping previous upgrades: %v", err)
+		} else {
+			*g.upgradeConfig = *upgrades
+			log.Printf("Reloaded %s", upgradesPath)
+		}
+	}
+}
+
+// modTime returns a file's modification time, or the zero time if it can't be read
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,10 @@
+//go:build !dev
+
+package main
+
+// dataWatcher is empty in release builds; data files are only read at startup
+type dataWatcher struct{}
+
+// watchDataFiles does nothing in release builds. Build with -tags dev to
+// reload tuning, enemy, wave, level and particle data while the game is running.
+func (g *Game) watchDataFiles() {}
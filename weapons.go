@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2/audio/mp3"
+	"github.com/hajimehoshi/ebiten/v2/audio/wav"
+)
+
+const weaponsPath = "assets/weapons.json"
+
+// WeaponAttack describes one step of a weapon's attack combo
+type WeaponAttack struct {
+	Tag           string     `json:"tag"`           // Animation tag in Soldier.aseprite
+	Damage        int        `json:"damage"`        // Health taken from each enemy hit
+	Reach         [2]float64 `json:"reach"`         // Width and height of the attack box in sprite pixels; unused by ranged weapons
+	Knockback     float64    `json:"knockback"`     // Knockback velocity given to enemies on hit
+	FrameDuration float64    `json:"frameDuration"` // Seconds per animation frame, which sets the swing speed
+	ActiveFrames  [2]int     `json:"activeFrames"`  // First and last frame that can hit, relative to the tag start; ranged weapons shoot on the first
+	Knockdown     bool       `json:"knockdown"`     // Knocks enemies off their feet
+}
+
+// WeaponProjectile is what a ranged weapon shoots. Damage and knockback come
+// from the attack that shoots it.
+type WeaponProjectile struct {
+	Speed    float64    `json:"speed"`    // Pixels per tick
+	Gravity  float64    `json:"gravity"`  // Added to the vertical speed every tick; 0 flies straight
+	Lifetime float64    `json:"lifetime"` // Seconds before it disappears
+	Width    float64    `json:"width"`
+	Height   float64    `json:"height"`
+	Color    [3]float64 `json:"color"` // RGB from 0 to 1
+}
+
+// WeaponDefinition describes a weapon the soldier can wield
+type WeaponDefinition struct {
+	Name   string   `json:"name"`   // Shown in the HUD and when it's picked up
+	Icon   string   `json:"icon"`   // Tag of its icon in the pickup sprite
+	Sound  string   `json:"sound"`  // .mp3 or .wav played on every attack; empty for the default swing
+	Layers []string `json:"layers"` // Layers of Soldier.aseprite drawn while it's wielded; empty draws them all
+
+	// Pressing attack during a swing, or soon after it, chains into the next
+	// step. The last step is the finisher.
+	Combo []WeaponAttack `json:"combo"`
+
+	Projectile *WeaponProjectile `json:"projectile,omitempty"` // If set, attacks shoot this instead of striking in melee
+}
+
+// WeaponConfig is everything in weaponsPath
+type WeaponConfig struct {
+	Default string                       `json:"default"` // Weapon the soldier starts with
+	Weapons map[string]*WeaponDefinition `json:"weapons"`
+}
+
+// LoadWeaponConfig reads and validates the weapon data file
+func LoadWeaponConfig(path string) (*WeaponConfig, error) {
+	config := &WeaponConfig{}
+	if err := decodeDataFile(path, "weapon", config, config.Validate); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate checks every weapon and that the default one exists
+func (c *WeaponConfig) Validate() error {
+	var errs []error
+	if _, ok := c.Weapons[c.Default]; !ok {
+		errs = append(errs, fmt.Errorf("default weapon %q is not defined", c.Default))
+	}
+
+	// Sorted so errors come out in the same order every time
+	for _, name := range sortedKeys(c.Weapons) {
+		if err := c.Weapons[name].Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("weapon %q: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CheckSprite checks that every animation tag and layer the weapons name is in
+// the player's sprite, given functions that report whether it has a tag or layer
+func (c *WeaponConfig) CheckSprite(hasTag, hasLayer func(string) bool) error {
+	var errs []error
+	for _, name := range sortedKeys(c.Weapons) {
+		weapon := c.Weapons[name]
+		for i, attack := range weapon.Combo {
+			if !hasTag(attack.Tag) {
+				errs = append(errs, fmt.Errorf("weapon %q: combo step %d tag %q is not a tag in Soldier.aseprite", name, i+1, attack.Tag))
+			}
+		}
+		for _, layer := range weapon.Layers {
+			if !hasLayer(layer) {
+				errs = append(errs, fmt.Errorf("weapon %q: layer %q is not a layer in Soldier.aseprite", name, layer))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Has reports whether a weapon is defined
+func (c *WeaponConfig) Has(name string) bool {
+	_, ok := c.Weapons[name]
+	return ok
+}
+
+// Validate checks the weapon's combo and projectile
+func (d *WeaponDefinition) Validate() error {
+	var errs []error
+	positive := func(name string, v float64) {
+		if v <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive, got %v", name, v))
+		}
+	}
+	nonNegative := func(name string, v float64) {
+		if v < 0 {
+			errs = append(errs, fmt.Errorf("%s cannot be negative, got %v", name, v))
+		}
+	}
+
+	if d.Name == "" {
+		errs = append(errs, errors.New("name is required"))
+	}
+	if d.Icon == "" {
+		errs = append(errs, errors.New("icon is required"))
+	}
+	if ext := filepath.Ext(d.Sound); d.Sound != "" && ext != ".mp3" && ext != ".wav" {
+		errs = append(errs, fmt.Errorf("sound must be an .mp3 or .wav file, got %q", d.Sound))
+	}
+
+	if len(d.Combo) == 0 {
+		errs = append(errs, errors.New("combo needs at least one attack"))
+	}
+	for i, attack := range d.Combo {
+		name := fmt.Sprintf("combo[%d]", i)
+		if attack.Tag == "" {
+			errs = append(errs, fmt.Errorf("%s.tag is required", name))
+		}
+		positive(name+".damage", float64(attack.Damage))
+		if d.Projectile == nil {
+			positive(name+".reach width", attack.Reach[0])
+			positive(name+".reach height", attack.Reach[1])
+		}
+		nonNegative(name+".knockback", attack.Knockback)
+		positive(name+".frameDuration", attack.FrameDuration)
+		if attack.ActiveFrames[0] < 0 || attack.ActiveFrames[1] < attack.ActiveFrames[0] {
+			errs = append(errs, fmt.Errorf("%s.activeFrames must be an ascending pair of non-negative frames, got %v", name, attack.ActiveFrames))
+		}
+	}
+
+	if p := d.Projectile; p != nil {
+		positive("projectile.speed", p.Speed)
+		nonNegative("projectile.gravity", p.Gravity)
+		positive("projectile.lifetime", p.Lifetime)
+		positive("projectile.width", p.Width)
+		positive("projectile.height", p.Height)
+	}
+	return errors.Join(errs...)
+}
+
+// weapon returns the wielded weapon, or the default one if a reload removed it
+func (g *Game) weapon() *WeaponDefinition {
+	if weapon, ok := g.weapons.Weapons[g.weaponName]; ok {
+		return weapon
+	}
+	return g.weapons.Weapons[g.weapons.Default]
+}
+
+// equipWeapon swaps the wielded weapon, restarting the combo so the old
+// weapon's step count doesn't carry over
+func (g *Game) equipWeapon(name string) {
+	if !g.weapons.Has(name) || name == g.weaponName {
+		return
+	}
+	g.weaponName = name
+	g.isAttacking = false
+	g.breakCombo()
+	g.refreshPlayerSprite()
+}
+
+// meleeAttackActive reports whether the player's swing is on a frame that hits
+// what's in front of them. Ranged weapons shoot instead.
+func (g *Game) meleeAttackActive() bool {
+	return g.weapon().Projectile == nil && g.playerAttackActive()
+}
+
+// shootWeapon fires a ranged weapon's projectile once per swing, as the swing
+// reaches its active frames
+func (g *Game) shootWeapon() {
+	def := g.weapon().Projectile
+	if def == nil || !g.playerAttackActive() || g.shotSwing == g.swingID {
+		return
+	}
+	g.shotSwing = g.swingID
+	hit := g.playerHit()
+
+	direction := facingDirection(g.facingLeft)
+	bodySize := g.tuning.Player.BodySize * playerSpriteScale
+	g.projectiles = append(g.projectiles, &Projectile{
+		X:         g.positionX + direction*bodySize/2,
+		Y:         g.positionY, // Shot from the chest
+		Depth:     g.positionY,
+		VX:        direction * def.Speed,
+		Gravity:   def.Gravity,
+		Lifetime:  def.Lifetime,
+		Owner:     OwnerPlayer,
+		Damage:    float64(hit.Damage),
+		Knockback: hit.Knockback,
+		Width:     def.Width,
+		Height:    def.Height,
+		Color:     def.Color,
+	})
+}
+
+// playAttackSound plays the wielded weapon's sound, or the default swing
+func (g *Game) playAttackSound() {
+	player := g.attackPlayer
+	if weaponPlayer, ok := g.weaponSounds[g.weapon().Sound]; ok {
+		player = weaponPlayer
+	}
+	if player != nil {
+		player.Rewind()
+		player.Play()
+	}
+}
+
+// loadSound loads an .mp3 or .wav file as a sound effect player
+func (g *Game) loadSound(path string) (*audio.Player, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", path, err)
+	}
+
+	var stream io.ReadSeeker
+	if filepath.Ext(path) == ".wav" {
+		stream, err = wav.DecodeWithSampleRate(g.audioContext.SampleRate(), bytes.NewReader(data))
+	} else {
+		stream, err = mp3.DecodeWithoutResampling(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	player, err := g.audioContext.NewPlayer(stream)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create player for %s: %w", path, err)
+	}
+	return player, nil
+}
+
+// preloadWeaponSounds loads every weapon's sound up front. A missing sound
+// isn't fatal: that weapon plays the default swing.
+func (g *Game) preloadWeaponSounds() {
+	if g.weaponSounds == nil {
+		g.weaponSounds = map[string]*audio.Player{}
+	}
+	for _, name := range sortedKeys(g.weapons.Weapons) {
+		def := g.weapons.Weapons[name]
+		if def.Sound == "" {
+			continue
+		}
+		if _, ok := g.weaponSounds[def.Sound]; ok {
+			continue
+		}
+		player, err := g.loadSound(def.Sound)
+		if err != nil {
+			log.Printf("Weapon sound unavailable, the %s will use the default swing: %v", def.Name, err)
+			continue
+		}
+		player.SetVolume(g.settings.Audio.EffectiveEffectsVolume(attackSoundMix))
+		g.weaponSounds[def.Sound] = player
+	}
+}